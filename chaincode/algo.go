@@ -88,7 +88,12 @@ func queryAlgo(db *LedgerDB, args []string) (out outputAlgo, err error) {
 	if err != nil {
 		return
 	}
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	out.Fill(algo)
+	err = out.restrictDownload(db, algo, node)
 	return
 }
 
@@ -115,6 +120,10 @@ func queryAlgos(db *LedgerDB, args []string) (outAlgos []outputAlgo, bookmark st
 		return
 	}
 
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	for _, key := range elementsKeys {
 		algo, err := db.GetAlgo(key)
 		if err != nil {
@@ -122,6 +131,9 @@ func queryAlgos(db *LedgerDB, args []string) (outAlgos []outputAlgo, bookmark st
 		}
 		var out outputAlgo
 		out.Fill(algo)
+		if err = out.restrictDownload(db, algo, node); err != nil {
+			return outAlgos, bookmark, err
+		}
 		outAlgos = append(outAlgos, out)
 	}
 	return
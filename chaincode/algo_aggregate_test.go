@@ -69,8 +69,11 @@ func TestAggregateAlgo(t *testing.T) {
 				StorageAddress: inpAlgo.DescriptionStorageAddress,
 			},
 			Owner: workerA,
-			Permissions: outputPermissions{
-				Process: Permission{Public: true, AuthorizedIDs: []string{}},
+			Permissions: outputPermissionsFull{
+				outputPermissions: outputPermissions{
+					Process: Permission{Public: true, AuthorizedIDs: []string{}},
+				},
+				Download: Permission{Public: true, AuthorizedIDs: []string{}},
 			},
 			Metadata: map[string]string{},
 		},
@@ -88,7 +88,12 @@ func queryCompositeAlgo(db *LedgerDB, args []string) (out outputCompositeAlgo, e
 	if err != nil {
 		return
 	}
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	out.Fill(algo)
+	err = out.restrictDownload(db, algo.Algo, node)
 	return
 }
 
@@ -115,6 +120,10 @@ func queryCompositeAlgos(db *LedgerDB, args []string) (outAlgos []outputComposit
 		return
 	}
 
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	for _, key := range elementsKeys {
 		algo, err := db.GetCompositeAlgo(key)
 		if err != nil {
@@ -122,6 +131,9 @@ func queryCompositeAlgos(db *LedgerDB, args []string) (outAlgos []outputComposit
 		}
 		var out outputCompositeAlgo
 		out.Fill(algo)
+		if err = out.restrictDownload(db, algo.Algo, node); err != nil {
+			return outAlgos, bookmark, err
+		}
 		outAlgos = append(outAlgos, out)
 	}
 	return
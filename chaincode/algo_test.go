@@ -65,8 +65,11 @@ func TestAlgo(t *testing.T) {
 			StorageAddress: inpAlgo.DescriptionStorageAddress,
 		},
 		Owner: workerA,
-		Permissions: outputPermissions{
-			Process: Permission{Public: true, AuthorizedIDs: []string{}},
+		Permissions: outputPermissionsFull{
+			outputPermissions: outputPermissions{
+				Process: Permission{Public: true, AuthorizedIDs: []string{}},
+			},
+			Download: Permission{Public: true, AuthorizedIDs: []string{}},
 		},
 		Metadata: map[string]string{},
 	}
@@ -25,8 +25,12 @@ func (inpTraintuple *inputTraintuple) Fill(inpCP inputComputePlanTraintuple, IDT
 	inpTraintuple.DataSampleKeys = inpCP.DataSampleKeys
 	inpTraintuple.AlgoKey = inpCP.AlgoKey
 	inpTraintuple.Tag = inpCP.Tag
+	inpTraintuple.KeepModel = inpCP.KeepModel
 	inpTraintuple.Metadata = inpCP.Metadata
 
+	// Models registered outside of the compute plan come first
+	inpTraintuple.InModels = append(inpTraintuple.InModels, inpCP.InModelsKeys...)
+
 	// Set the inModels by matching the id to tuples key previously
 	// encontered in this compute plan
 	for _, InModelID := range inpCP.InModelsIDs {
@@ -44,6 +48,7 @@ func (inpAggregatetuple *inputAggregatetuple) Fill(inpCP inputComputePlanAggrega
 	inpAggregatetuple.Key = inpCP.Key
 	inpAggregatetuple.AlgoKey = inpCP.AlgoKey
 	inpAggregatetuple.Tag = inpCP.Tag
+	inpAggregatetuple.KeepModel = inpCP.KeepModel
 	inpAggregatetuple.Metadata = inpCP.Metadata
 	inpAggregatetuple.Worker = inpCP.Worker
 
@@ -66,6 +71,7 @@ func (inpCompositeTraintuple *inputCompositeTraintuple) Fill(inpCP inputComputeP
 	inpCompositeTraintuple.DataSampleKeys = inpCP.DataSampleKeys
 	inpCompositeTraintuple.AlgoKey = inpCP.AlgoKey
 	inpCompositeTraintuple.Tag = inpCP.Tag
+	inpCompositeTraintuple.KeepModel = inpCP.KeepModel
 	inpCompositeTraintuple.Metadata = inpCP.Metadata
 	inpCompositeTraintuple.OutTrunkModelPermissions = inpCP.OutTrunkModelPermissions
 
@@ -299,6 +305,114 @@ func queryComputePlans(db *LedgerDB, args []string) (outComputePlans []outputCom
 	return
 }
 
+// queryComputePlanTuples returns a page of the tuples of a compute plan,
+// optionally filtered by tuple type and, when the type is set, by status.
+// The status filter matches the stored status: waiting tuples of a failed or
+// canceled compute plan are listed as waiting but returned as aborted.
+func queryComputePlanTuples(db *LedgerDB, args []string) (outTuples []outputComputePlanTuple, bookmark string, err error) {
+	inp := inputQueryComputePlanTuples{}
+	outTuples = []outputComputePlanTuple{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	if _, err = db.GetComputePlan(inp.Key); err != nil {
+		return
+	}
+
+	attributes := []string{"computePlan", inp.Key}
+	if inp.Type != "" {
+		attributes = append(attributes, inp.Type)
+	}
+	if inp.Status != "" {
+		attributes = append(attributes, inp.Status)
+	}
+	tupleKeys, bookmark, err := db.GetIndexKeysWithPagination("computePlan~key~type~status~tupleKey", attributes, OutputPageSize, inp.Bookmark)
+	if err != nil {
+		return
+	}
+
+	for _, tupleKey := range tupleKeys {
+		var tuple GenericTuple
+		tuple, err = db.GetGenericTuple(tupleKey)
+		if err != nil {
+			return
+		}
+		outTuples = append(outTuples, outputComputePlanTuple{
+			Key:    tupleKey,
+			Type:   tuple.AssetType.String(),
+			Status: tuple.Status,
+			Rank:   tuple.Rank,
+		})
+	}
+	return
+}
+
+// queryComputePlanProgress returns, for each worker of a compute plan, the
+// number of tuples in each status by tuple type and the highest done rank.
+func queryComputePlanProgress(db *LedgerDB, args []string) (resp outputComputePlanProgress, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	computePlan, err := db.GetComputePlan(inp.Key)
+	if err != nil {
+		return
+	}
+
+	resp.Key = inp.Key
+	resp.Status = computePlan.State.Status
+	resp.Workers = []outputComputePlanWorkerProgress{}
+	progressByWorker := map[string]*outputComputePlanWorkerProgress{}
+	for _, worker := range computePlan.Workers {
+		wState, err := db.GetCPWorkerState(computePlan.getCPWorkerStateKey(worker))
+		if err != nil {
+			return resp, err
+		}
+		progress := outputComputePlanWorkerProgress{
+			Worker:          worker,
+			TupleCount:      wState.TupleCount,
+			DoneCount:       wState.DoneCount,
+			HighestDoneRank: -1,
+		}
+
+		tupleKeys, err := db.GetIndexKeys("computePlan~computeplankey~worker~rank~key", []string{"computePlan", inp.Key, worker})
+		if err != nil {
+			return resp, err
+		}
+		for _, tupleKey := range tupleKeys {
+			tuple, err := db.GetGenericTuple(tupleKey)
+			if err != nil {
+				return resp, err
+			}
+			progress.Add(tuple.AssetType, tuple.Status)
+			if tuple.Status == StatusDone && tuple.Rank > progress.HighestDoneRank {
+				progress.HighestDoneRank = tuple.Rank
+			}
+		}
+		progressByWorker[worker] = &progress
+	}
+
+	// Testtuples are not part of the rank index
+	for _, testtupleKey := range computePlan.TesttupleKeys {
+		testtuple, err := db.GetTesttuple(testtupleKey)
+		if err != nil {
+			return resp, err
+		}
+		progress, ok := progressByWorker[testtuple.Dataset.Worker]
+		if !ok {
+			return resp, errors.Internal("worker %s of testtuple %s not found in compute plan %s", testtuple.Dataset.Worker, testtupleKey, inp.Key)
+		}
+		progress.Add(TesttupleType, testtuple.Status)
+	}
+
+	for _, worker := range computePlan.Workers {
+		resp.Workers = append(resp.Workers, *progressByWorker[worker])
+	}
+	return resp, nil
+}
+
 // getComputePlan returns details for a compute plan key.
 // Traintuples, CompositeTraintuples and Aggregatetuples are ordered by ascending rank.
 func getOutComputePlan(db *LedgerDB, key string) (resp outputComputePlan, err error) {
@@ -358,7 +472,12 @@ func (cp *ComputePlan) Create(db *LedgerDB, key string) error {
 	cp.StateKey = GetRandomHash()
 	cp.AssetType = ComputePlanType
 	cp.Workers = []string{}
-	err := db.Add(key, cp)
+	creator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	cp.Creator = creator
+	err = db.Add(key, cp)
 	if err != nil {
 		return err
 	}
@@ -493,3 +612,24 @@ func UpdateComputePlanState(db *LedgerDB, ComputePlanKey, tupleStatus, tupleKey
 	}
 	return nil
 }
+
+// createComputePlanTupleIndex registers a tuple in the index listing the
+// members of its compute plan by type and status
+func createComputePlanTupleIndex(db *LedgerDB, computePlanKey string, tupleType AssetType, status, tupleKey string) error {
+	if computePlanKey == "" {
+		return nil
+	}
+	return db.CreateIndex("computePlan~key~type~status~tupleKey", []string{"computePlan", computePlanKey, tupleType.String(), status, tupleKey})
+}
+
+// updateComputePlanTupleIndex moves a tuple from its old status to its new
+// one in the index listing the members of its compute plan
+func updateComputePlanTupleIndex(db *LedgerDB, computePlanKey string, tupleType AssetType, oldStatus, newStatus, tupleKey string) error {
+	if computePlanKey == "" {
+		return nil
+	}
+	indexName := "computePlan~key~type~status~tupleKey"
+	oldAttributes := []string{"computePlan", computePlanKey, tupleType.String(), oldStatus, tupleKey}
+	newAttributes := []string{"computePlan", computePlanKey, tupleType.String(), newStatus, tupleKey}
+	return db.UpdateIndex(indexName, oldAttributes, newAttributes)
+}
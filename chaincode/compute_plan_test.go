@@ -15,8 +15,11 @@
 package main
 
 import (
+	"net/http"
 	"testing"
 
+	"chaincode/errors"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -96,6 +99,8 @@ var (
 				DataManagerKey: dataManagerKey,
 				DataSampleKeys: []string{trainDataSampleKey1},
 				AlgoKey:        compositeAlgoKey,
+				// the trunk is shared with the aggregation worker
+				OutTrunkModelPermissions: OpenPermissions,
 			},
 			{
 				Key:            computePlanCompositeTraintupleKey2,
@@ -103,6 +108,8 @@ var (
 				DataManagerKey: dataManagerKey2,
 				DataSampleKeys: []string{trainDataSampleKeyWorker2},
 				AlgoKey:        compositeAlgoKey,
+				// the trunk is shared with the aggregation worker
+				OutTrunkModelPermissions: OpenPermissions,
 			},
 			{
 				Key:            computePlanCompositeTraintupleKey3,
@@ -110,8 +117,10 @@ var (
 				DataManagerKey: dataManagerKey,
 				DataSampleKeys: []string{trainDataSampleKey1},
 				AlgoKey:        compositeAlgoKey,
-				InHeadModelID:  "step_1_composite_A",
-				InTrunkModelID: "step_2_aggregate",
+				// the trunk is shared with the aggregation worker
+				OutTrunkModelPermissions: OpenPermissions,
+				InHeadModelID:            "step_1_composite_A",
+				InTrunkModelID:           "step_2_aggregate",
 			},
 			{
 				Key:            computePlanCompositeTraintupleKey4,
@@ -119,8 +128,10 @@ var (
 				DataManagerKey: dataManagerKey2,
 				DataSampleKeys: []string{trainDataSampleKeyWorker2},
 				AlgoKey:        compositeAlgoKey,
-				InHeadModelID:  "step_1_composite_B",
-				InTrunkModelID: "step_2_aggregate",
+				// the trunk is shared with the aggregation worker
+				OutTrunkModelPermissions: OpenPermissions,
+				InHeadModelID:            "step_1_composite_B",
+				InTrunkModelID:           "step_2_aggregate",
 			},
 		},
 		Aggregatetuples: []inputComputePlanAggregatetuple{
@@ -290,6 +301,66 @@ func TestModelCompositionComputePlanWorkflow(t *testing.T) {
 	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[3].composite[1].Trunk)
 }
 
+func TestKeepModelComputePlanWorkflow(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// pin the first aggregate at creation
+	inpCP := modelCompositionComputePlan
+	inpCP.Aggregatetuples = append([]inputComputePlanAggregatetuple{}, modelCompositionComputePlan.Aggregatetuples...)
+	inpCP.Aggregatetuples[0].KeepModel = true
+	out, err := createComputePlanInternal(db, inpCP, tag, map[string]string{}, true)
+	require.NoError(t, err)
+
+	aggregatetuple, err := queryAggregatetuple(db, assetToArgs(inputKey{Key: out.AggregatetupleKeys[0]}))
+	require.NoError(t, err)
+	assert.True(t, aggregatetuple.KeepModel)
+
+	// and the first composite of step 3 afterwards, which only its creator can do
+	mockStub.Creator = workerB
+	_, err = updateKeepModel(db, assetToArgs(inputUpdateKeepModel{Key: out.CompositeTraintupleKeys[2], KeepModel: true}))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+	mockStub.Creator = workerA
+	_, err = updateKeepModel(db, assetToArgs(inputUpdateKeepModel{Key: out.CompositeTraintupleKeys[2], KeepModel: true}))
+	require.NoError(t, err)
+
+	step := map[int]TestModels{
+		1: {composite: []TestCompositeModel{
+			{Head: RandomUUID(), Trunk: RandomUUID()},
+			{Head: RandomUUID(), Trunk: RandomUUID()}}},
+		2: {Aggregate: RandomUUID()},
+		3: {composite: []TestCompositeModel{
+			{Head: RandomUUID(), Trunk: RandomUUID()},
+			{Head: RandomUUID(), Trunk: RandomUUID()}}},
+		4: {Aggregate: RandomUUID()},
+	}
+
+	compositeToDone(t, mockStub, workerA, db, out.CompositeTraintupleKeys[0], step[1].composite[0].Head, step[1].composite[0].Trunk)
+	compositeToDone(t, mockStub, workerB, db, out.CompositeTraintupleKeys[1], step[1].composite[1].Head, step[1].composite[1].Trunk)
+	testtupleToDone(t, db, out.TesttupleKeys[0])
+	testtupleToDone(t, db, out.TesttupleKeys[1])
+	aggregateToDone(t, mockStub, workerC, db, out.AggregatetupleKeys[0], step[2].Aggregate)
+	testtupleToDone(t, db, out.TesttupleKeys[2])
+	compositeToDone(t, mockStub, workerA, db, out.CompositeTraintupleKeys[2], step[3].composite[0].Head, step[3].composite[0].Trunk)
+	compositeToDone(t, mockStub, workerB, db, out.CompositeTraintupleKeys[3], step[3].composite[1].Head, step[3].composite[1].Trunk)
+	testtupleToDone(t, db, out.TesttupleKeys[3])
+	testtupleToDone(t, db, out.TesttupleKeys[4])
+
+	// the pinned aggregate is not scheduled for deletion once unused
+	aggregateToDone(t, mockStub, workerC, db, out.AggregatetupleKeys[1], step[4].Aggregate)
+	assert.Len(t, db.event.ComputePlans, 0)
+
+	// nor are the pinned composite models when the compute plan is done
+	testtupleToDone(t, db, out.TesttupleKeys[5])
+	require.Len(t, db.event.ComputePlans, 1)
+	assert.Equal(t, StatusDone, db.event.ComputePlans[0].Status)
+	assert.Len(t, db.event.ComputePlans[0].ModelsToDelete, 2)
+	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[3].composite[1].Head)
+	assert.Contains(t, db.event.ComputePlans[0].ModelsToDelete, step[3].composite[1].Trunk)
+}
+
 func validateTupleRank(t *testing.T, db *LedgerDB, expectedRank int, key string, assetType AssetType) {
 	inp := inputKey{Key: key}
 	rank := -42
@@ -694,17 +765,112 @@ func TestComputePlanMetrics(t *testing.T) {
 	assert.NoError(t, err)
 	checkComputePlanMetrics(t, db, out.Key, 0, 3)
 
-	traintupleToDone(t, db, out.TraintupleKeys[0])
+	traintupleToDone(t, db, out.TraintupleKeys[0], modelKey)
 	checkComputePlanMetrics(t, db, out.Key, 1, 3)
 
-	traintupleToDone(t, db, out.TraintupleKeys[1])
+	traintupleToDone(t, db, out.TraintupleKeys[1], RandomUUID())
 	checkComputePlanMetrics(t, db, out.Key, 2, 3)
 
 	testtupleToDone(t, db, out.TesttupleKeys[0])
 	checkComputePlanMetrics(t, db, out.Key, 3, 3)
 }
 
-func traintupleToDone(t *testing.T, db *LedgerDB, key string) {
+func TestQueryComputePlanProgress(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	assert.NoError(t, err)
+
+	traintupleToDone(t, db, out.TraintupleKeys[0], modelKey)
+
+	progress, err := queryComputePlanProgress(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, out.Key, progress.Key)
+	assert.Equal(t, StatusDoing, progress.Status)
+	require.Len(t, progress.Workers, 1)
+
+	wProgress := progress.Workers[0]
+	assert.Equal(t, workerA, wProgress.Worker)
+	assert.Equal(t, 3, wProgress.TupleCount)
+	assert.Equal(t, 1, wProgress.DoneCount)
+	assert.Equal(t, 0, wProgress.HighestDoneRank)
+	assert.Equal(t, outputStatusCount{Done: 1, Todo: 1}, wProgress.Traintuples)
+	assert.Equal(t, outputStatusCount{Waiting: 1}, wProgress.Testtuples)
+	assert.Equal(t, outputStatusCount{}, wProgress.Aggregatetuples)
+
+	traintupleToDone(t, db, out.TraintupleKeys[1], RandomUUID())
+	progress, err = queryComputePlanProgress(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, progress.Workers[0].HighestDoneRank)
+	assert.Equal(t, outputStatusCount{Todo: 1}, progress.Workers[0].Testtuples)
+}
+
+func TestQueryComputePlanProgressCanceled(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	assert.NoError(t, err)
+	_, err = cancelComputePlan(db, keyToArgs(out.Key))
+	require.NoError(t, err)
+
+	progress, err := queryComputePlanProgress(db, keyToArgs(out.Key))
+	assert.NoError(t, err)
+	assert.Equal(t, StatusCanceled, progress.Status)
+	require.Len(t, progress.Workers, 1)
+
+	// the waiting tuples are aborted and counted with the canceled ones
+	wProgress := progress.Workers[0]
+	assert.Equal(t, outputStatusCount{Todo: 1, Canceled: 1}, wProgress.Traintuples)
+	assert.Equal(t, outputStatusCount{Canceled: 1}, wProgress.Testtuples)
+	count := 0
+	for _, c := range []outputStatusCount{wProgress.Traintuples, wProgress.CompositeTraintuples, wProgress.Aggregatetuples, wProgress.Testtuples} {
+		count += c.Waiting + c.Todo + c.Doing + c.Done + c.Failed + c.Canceled
+	}
+	assert.Equal(t, wProgress.TupleCount, count)
+}
+
+func TestQueryComputePlanTuples(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	assert.NoError(t, err)
+	traintupleToDone(t, db, out.TraintupleKeys[0], modelKey)
+
+	tuples, _, err := queryComputePlanTuples(db, assetToArgs(inputQueryComputePlanTuples{Key: out.Key}))
+	assert.NoError(t, err)
+	assert.Len(t, tuples, 3)
+
+	tuples, _, err = queryComputePlanTuples(db, assetToArgs(inputQueryComputePlanTuples{Key: out.Key, Type: "traintuple"}))
+	assert.NoError(t, err)
+	assert.Len(t, tuples, 2)
+
+	tuples, _, err = queryComputePlanTuples(db, assetToArgs(inputQueryComputePlanTuples{Key: out.Key, Type: "traintuple", Status: StatusTodo}))
+	assert.NoError(t, err)
+	require.Len(t, tuples, 1)
+	assert.Equal(t, outputComputePlanTuple{Key: out.TraintupleKeys[1], Type: "traintuple", Status: StatusTodo, Rank: 1}, tuples[0])
+
+	tuples, _, err = queryComputePlanTuples(db, assetToArgs(inputQueryComputePlanTuples{Key: out.Key, Type: "testtuple", Status: StatusWaiting}))
+	assert.NoError(t, err)
+	require.Len(t, tuples, 1)
+	assert.Equal(t, out.TesttupleKeys[0], tuples[0].Key)
+
+	_, _, err = queryComputePlanTuples(db, assetToArgs(inputQueryComputePlanTuples{Key: out.Key, Status: StatusTodo}))
+	assert.Error(t, err, "status filter requires a type")
+}
+
+func traintupleToDone(t *testing.T, db *LedgerDB, key string, modelKey string) {
 	_, err := logStartTrain(db, assetToArgs(inputKey{Key: key}))
 	assert.NoError(t, err)
 	clearEvent(db)
@@ -909,6 +1075,8 @@ func compositeToDone(t *testing.T, mockStub *MockStub, worker string, db *Ledger
 	inpLogCompo.fillDefaults()
 	inpLogCompo.OutHeadModel.Key = headModelKey
 	inpLogCompo.OutTrunkModel.Key = trunkModelKey
+	inpLogCompo.OutHeadModel.Checksum = GetRandomHash()
+	inpLogCompo.OutTrunkModel.Checksum = GetRandomHash()
 	inpLogCompo.Key = key
 	comp, err := logSuccessCompositeTrain(db, assetToArgs(inpLogCompo))
 	assert.NoError(t, err)
@@ -927,6 +1095,7 @@ func aggregateToDone(t *testing.T, mockStub *MockStub, worker string, db *Ledger
 	inpLogAgg := inputLogSuccessTrain{}
 	inpLogAgg.fillDefaults()
 	inpLogAgg.OutModel.Key = modelKey
+	inpLogAgg.OutModel.Checksum = GetRandomHash()
 	inpLogAgg.Key = key
 	agg, err := logSuccessAggregate(db, assetToArgs(inpLogAgg))
 	assert.NoError(t, err)
@@ -108,7 +108,7 @@ func (cp *ComputePlan) getCPWorkerStateKey(worker string) string {
 
 // removeAllIntermediaryModels iterates through all the worker states, and clears the lists of
 // intermediary models. It returns the concatenated list of all the intermediary models that
-// have been removed from the worker states.
+// have been removed from the worker states, except the pinned ones which must be kept.
 func (cp *ComputePlan) removeAllIntermediaryModels(db *LedgerDB) ([]string, error) {
 	res := []string{}
 	for _, worker := range cp.Workers {
@@ -117,7 +117,15 @@ func (cp *ComputePlan) removeAllIntermediaryModels(db *LedgerDB) ([]string, erro
 		if err != nil {
 			return []string{}, err
 		}
-		res = append(res, wState.IntermediaryModelsInUse...)
+		for _, modelKey := range wState.IntermediaryModelsInUse {
+			pinned, err := isModelPinned(db, modelKey)
+			if err != nil {
+				return []string{}, err
+			}
+			if !pinned {
+				res = append(res, modelKey)
+			}
+		}
 		wState.IntermediaryModelsInUse = []string{}
 
 		// clear
@@ -131,6 +139,7 @@ func (cp *ComputePlan) removeAllIntermediaryModels(db *LedgerDB) ([]string, erro
 
 // getModelsInUse takes a list of model keys and checks whether these models are still in use or not.
 // It returns the initial list split into two sublists: the models in use, and the models unused.
+// Pinned models are always considered in use, so that they are never scheduled for deletion.
 func getModelsInUse(db *LedgerDB, modelKeys []string) (usedModels []string, unusedModels []string, err error) {
 	usedModels = []string{}
 	unusedModels = []string{}
@@ -139,6 +148,12 @@ func getModelsInUse(db *LedgerDB, modelKeys []string) (usedModels []string, unus
 		if err != nil {
 			return []string{}, []string{}, err
 		}
+		if !inUse {
+			inUse, err = isModelPinned(db, modelKey)
+			if err != nil {
+				return []string{}, []string{}, err
+			}
+		}
 		if inUse {
 			usedModels = append(usedModels, modelKey)
 		} else {
@@ -176,6 +191,23 @@ func isModelInUse(db *LedgerDB, modelKey string) (bool, error) {
 	return false, nil
 }
 
+// isModelPinned returns true if the tuple producing the model with the supplied
+// key was marked to keep its out-models.
+func isModelPinned(db *LedgerDB, modelKey string) (bool, error) {
+	keys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", modelKey})
+	if err != nil {
+		return false, err
+	}
+	if len(keys) == 0 {
+		return false, nil
+	}
+	tuple, err := db.GetGenericTuple(keys[0])
+	if err != nil {
+		return false, err
+	}
+	return tuple.KeepModel, nil
+}
+
 // getTupleChildren returns the keys of all the tuples which have the supplied tuple as an in-model
 // If includeTesttuples is True, also include the testtuple children, else omit them.
 func getTupleChildren(db *LedgerDB, tupleKey string, includeTesttuples bool) ([]string, error) {
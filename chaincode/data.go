@@ -82,7 +82,8 @@ func setDataSample(db *LedgerDB, inp inputDataSample) (dataSampleKeys []string,
 		AssetType:       DataSampleType,
 		DataManagerKeys: dataManagerKeys,
 		TestOnly:        testOnly,
-		Owner:           owner}
+		Owner:           owner,
+		Metadata:        inp.Metadata}
 
 	return
 }
@@ -225,6 +226,223 @@ func updateDataSample(db *LedgerDB, args []string) (resp outputKey, err error) {
 	return outputKey{Key: keysJSON}, nil
 }
 
+// queryDataSampleUsage returns the traintuples, composite traintuples and
+// testtuples which used a dataSample, along with the models they produced
+func queryDataSampleUsage(db *LedgerDB, args []string) (outUsages []outputDataSampleUsage, bookmark string, err error) {
+	outUsages = []outputDataSampleUsage{}
+	inp := inputQueryDataSampleUsage{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	if _, err = db.GetDataSample(inp.Key); err != nil {
+		return
+	}
+	tupleKeys, bookmark, err := db.GetIndexKeysWithPagination("dataSample~tuple~key", []string{"dataSample", inp.Key}, OutputPageSize, inp.Bookmark)
+	if err != nil {
+		return
+	}
+	for _, tupleKey := range tupleKeys {
+		var out outputDataSampleUsage
+		if err = out.Fill(db, tupleKey); err != nil {
+			return
+		}
+		outUsages = append(outUsages, out)
+	}
+	return
+}
+
+// unlinkDataSample dissociates one or more dataManagers from one or more
+// dataSample. Only the dataSample owner can remove these links, and not while
+// a dataSample is used by a tuple which is not over or belongs to the test
+// dataset of the objective of one of these dataManagers.
+func unlinkDataSample(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputUpdateDataSample{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	for _, dataSampleKey := range inp.Keys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleOwner(db, dataSample); err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleNotInUse(db, dataSampleKey); err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleNotInTestDataset(db, dataSampleKey, inp.DataManagerKeys); err != nil {
+			return nil, err
+		}
+		dataManagerKeys := []string{}
+		for _, dataManagerKey := range dataSample.DataManagerKeys {
+			if !stringInSlice(dataManagerKey, inp.DataManagerKeys) {
+				dataManagerKeys = append(dataManagerKeys, dataManagerKey)
+			}
+		}
+		for _, dataManagerKey := range inp.DataManagerKeys {
+			if !stringInSlice(dataManagerKey, dataSample.DataManagerKeys) {
+				return nil, errors.BadRequest("dataSample %s is not associated with dataManager %s", dataSampleKey, dataManagerKey)
+			}
+			if err = db.DeleteIndex("dataSample~dataManager~key", []string{"dataSample", dataManagerKey, dataSampleKey}); err != nil {
+				return nil, err
+			}
+			if err = db.DeleteIndex("dataSample~dataManager~testOnly~key", []string{"dataSample", dataManagerKey, strconv.FormatBool(dataSample.TestOnly), dataSampleKey}); err != nil {
+				return nil, err
+			}
+		}
+		dataSample.DataManagerKeys = dataManagerKeys
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
+// updateDataSampleTestOnly changes the testOnly flag of one or more dataSample.
+// Only the dataSample owner can change it, and not while a dataSample is used
+// by a tuple which is not over or belongs to the test dataset of an objective.
+func updateDataSampleTestOnly(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputUpdateDataSampleTestOnly{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	testOnly, err := strconv.ParseBool(inp.TestOnly)
+	if err != nil {
+		return
+	}
+	for _, dataSampleKey := range inp.Keys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleOwner(db, dataSample); err != nil {
+			return nil, err
+		}
+		if dataSample.TestOnly == testOnly {
+			continue
+		}
+		if err = checkDataSampleNotInUse(db, dataSampleKey); err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleNotInTestDataset(db, dataSampleKey, dataSample.DataManagerKeys); err != nil {
+			return nil, err
+		}
+		for _, dataManagerKey := range dataSample.DataManagerKeys {
+			oldAttributes := []string{"dataSample", dataManagerKey, strconv.FormatBool(dataSample.TestOnly), dataSampleKey}
+			newAttributes := []string{"dataSample", dataManagerKey, strconv.FormatBool(testOnly), dataSampleKey}
+			if err = db.UpdateIndex("dataSample~dataManager~testOnly~key", oldAttributes, newAttributes); err != nil {
+				return nil, err
+			}
+		}
+		dataSample.TestOnly = testOnly
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
+// proposeDataSampleTransfer proposes to transfer the ownership of one or more
+// dataSample to another node, which has to accept it. Only the owner can
+// propose a transfer, and a new proposal replaces the pending one.
+func proposeDataSampleTransfer(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputDataSampleTransfer{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	if err = validateAuthorizedIds(db, []string{inp.NewOwner}); err != nil {
+		return nil, errors.BadRequest(err, "invalid new owner %s", inp.NewOwner)
+	}
+	for _, dataSampleKey := range inp.Keys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleOwner(db, dataSample); err != nil {
+			return nil, err
+		}
+		if inp.NewOwner == dataSample.Owner {
+			return nil, errors.BadRequest("%s already owns the dataSample %s", inp.NewOwner, dataSampleKey)
+		}
+		dataSample.PendingOwner = inp.NewOwner
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
+// acceptDataSampleTransfer makes the transaction creator the owner of one or
+// more dataSample it was proposed. The previous owner has to unlink the dataSample
+// from the dataManagers the transaction creator doesn't own beforehand.
+func acceptDataSampleTransfer(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputDataSampleKeys{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	for _, dataSampleKey := range inp.Keys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if dataSample.PendingOwner != txCreator {
+			return nil, errors.Forbidden("the transfer of dataSample %s was not proposed to %s", dataSampleKey, txCreator)
+		}
+		for _, dataManagerKey := range dataSample.DataManagerKeys {
+			owner, err := getDataManagerOwner(db, dataManagerKey)
+			if err != nil {
+				return nil, err
+			}
+			if owner != txCreator {
+				return nil, errors.BadRequest("dataSample %s is still associated with dataManager %s of %s, it must be unlinked before accepting the transfer", dataSampleKey, dataManagerKey, owner)
+			}
+		}
+		dataSample.Owner = txCreator
+		dataSample.PendingOwner = ""
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
+// cancelDataSampleTransfer withdraws the pending transfer of one or more
+// dataSample. Only the owner can cancel a transfer.
+func cancelDataSampleTransfer(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputDataSampleKeys{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	for _, dataSampleKey := range inp.Keys {
+		dataSample, err := db.GetDataSample(dataSampleKey)
+		if err != nil {
+			return nil, err
+		}
+		if err = checkDataSampleOwner(db, dataSample); err != nil {
+			return nil, err
+		}
+		if dataSample.PendingOwner == "" {
+			return nil, errors.BadRequest("no pending transfer for dataSample %s", dataSampleKey)
+		}
+		dataSample.PendingOwner = ""
+		if err = db.Put(dataSampleKey, dataSample); err != nil {
+			return nil, err
+		}
+	}
+	return map[string][]string{"keys": inp.Keys}, nil
+}
+
 // updateDataManager associates a objectiveKey to an existing dataManager
 func updateDataManager(db *LedgerDB, args []string) (resp outputKey, err error) {
 	inp := inputUpdateDataManager{}
@@ -255,7 +473,12 @@ func queryDataManager(db *LedgerDB, args []string) (out outputDataManager, err e
 		err = errors.NotFound("no element with key %s", inp.Key)
 		return
 	}
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	out.Fill(dataManager)
+	err = out.restrictDownload(db, dataManager, node)
 	return
 }
 
@@ -282,6 +505,10 @@ func queryDataManagers(db *LedgerDB, args []string) (outDataManagers []outputDat
 		return
 	}
 
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
 	for _, key := range elementsKeys {
 		dataManager, err := db.GetDataManager(key)
 		if err != nil {
@@ -289,6 +516,9 @@ func queryDataManagers(db *LedgerDB, args []string) (outDataManagers []outputDat
 		}
 		var out outputDataManager
 		out.Fill(dataManager)
+		if err = out.restrictDownload(db, dataManager, node); err != nil {
+			return outDataManagers, bookmark, err
+		}
 		outDataManagers = append(outDataManagers, out)
 	}
 	return
@@ -320,8 +550,13 @@ func queryDataset(db *LedgerDB, args []string) (outputDataset, error) {
 		return out, err
 	}
 
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return out, err
+	}
 	out.Fill(dataManager, trainDataSampleKeys, testDataSampleKeys)
-	return out, nil
+	err = out.restrictDownload(db, dataManager, node)
+	return out, err
 }
 
 func queryDataSamples(db *LedgerDB, args []string) (outDataSamples []outputDataSample, bookmark string, err error) {
@@ -397,6 +632,64 @@ func checkDataSampleOwner(db *LedgerDB, dataSample DataSample) error {
 	return nil
 }
 
+// checkDataSampleNotInUse returns an error if the dataSample is used by a tuple
+// waiting, todo or doing
+func checkDataSampleNotInUse(db *LedgerDB, dataSampleKey string) error {
+	tupleKeys, err := db.GetIndexKeys("dataSample~tuple~key", []string{"dataSample", dataSampleKey})
+	if err != nil {
+		return err
+	}
+	for _, tupleKey := range tupleKeys {
+		tuple, err := db.GetGenericTuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		if stringInSlice(tuple.Status, []string{StatusWaiting, StatusTodo, StatusDoing}) {
+			return errors.BadRequest("dataSample %s is used by tuple %s with status %s", dataSampleKey, tupleKey, tuple.Status)
+		}
+	}
+	return nil
+}
+
+// checkDataSampleNotInTestDataset returns an error if the dataSample belongs to
+// the test dataset of any version of the objective of one of the dataManagers
+func checkDataSampleNotInTestDataset(db *LedgerDB, dataSampleKey string, dataManagerKeys []string) error {
+	for _, dataManagerKey := range dataManagerKeys {
+		dataManager, err := db.GetDataManager(dataManagerKey)
+		if err != nil {
+			return err
+		}
+		if dataManager.ObjectiveKey == "" {
+			continue
+		}
+		objective, err := db.GetObjective(dataManager.ObjectiveKey)
+		if err != nil {
+			return err
+		}
+		for version := 1; version <= objective.Version; version++ {
+			objectiveVersion, err := db.GetObjectiveVersion(objective.Key, version)
+			if err != nil {
+				return err
+			}
+			testDataset := objectiveVersion.TestDataset
+			if testDataset != nil && stringInSlice(dataSampleKey, testDataset.DataSampleKeys) {
+				return errors.BadRequest("dataSample %s belongs to the test dataset of objective %s version %d", dataSampleKey, objective.Key, version)
+			}
+		}
+	}
+	return nil
+}
+
+// createDataSampleUsageIndex registers a tuple as a user of its dataSample
+func createDataSampleUsageIndex(db *LedgerDB, dataSampleKeys []string, tupleKey string) error {
+	for _, dataSampleKey := range dataSampleKeys {
+		if err := db.CreateIndex("dataSample~tuple~key", []string{"dataSample", dataSampleKey, tupleKey}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // checkSameDataManager checks if dataSample in a slice exist and are from the same dataManager.
 // If yes, returns two boolean indicating if dataSample are testOnly and trainOnly
 func checkSameDataManager(db *LedgerDB, dataManagerKey string, dataSampleKeys []string) (bool, bool, error) {
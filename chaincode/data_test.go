@@ -19,6 +19,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestJsonInputsDataManager(t *testing.T) {
@@ -80,8 +81,11 @@ func TestDataManager(t *testing.T) {
 			StorageAddress: inpDataManager.DescriptionStorageAddress,
 			Checksum:       inpDataManager.DescriptionChecksum,
 		},
-		Permissions: outputPermissions{
-			Process: Permission{Public: true, AuthorizedIDs: []string{}},
+		Permissions: outputPermissionsFull{
+			outputPermissions: outputPermissions{
+				Process: Permission{Public: true, AuthorizedIDs: []string{}},
+			},
+			Download: Permission{Public: true, AuthorizedIDs: []string{}},
 		},
 		Opener: &ChecksumAddress{
 			Checksum:       inpDataManager.OpenerChecksum,
@@ -195,3 +199,185 @@ func TestDataset(t *testing.T) {
 	assert.ElementsMatch(t, out.TrainDataSampleKeys, inpDataSample.Keys, "when querying dataManager dataSample, unexpected train keys")
 
 }
+
+func TestDataSampleTransfer(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "algo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	key := RandomUUID()
+	inpDataSample := inputDataSample{Keys: []string{key}, DataManagerKeys: []string{dataManagerKey}}
+	inpDataSample.createDefault()
+	inpDataSample.Metadata = map[string]string{"curated_by": "team A"}
+	_, err := registerDataSample(db, assetToArgs(inpDataSample))
+	require.NoError(t, err)
+	dataSample, err := db.GetDataSample(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "team A", dataSample.Metadata["curated_by"])
+
+	_, err = proposeDataSampleTransfer(db, assetToArgs(inputDataSampleTransfer{Keys: []string{key}, NewOwner: "unknown"}))
+	assert.Error(t, err, "the new owner should be a registered node")
+
+	mockStub.Creator = workerB
+	_, err = proposeDataSampleTransfer(db, assetToArgs(inputDataSampleTransfer{Keys: []string{key}, NewOwner: workerB}))
+	assert.Error(t, err, "only the owner can propose a transfer")
+	_, err = acceptDataSampleTransfer(db, assetToArgs(inputDataSampleKeys{Keys: []string{key}}))
+	assert.Error(t, err, "a transfer should be proposed before being accepted")
+
+	mockStub.Creator = workerA
+	_, err = proposeDataSampleTransfer(db, assetToArgs(inputDataSampleTransfer{Keys: []string{key}, NewOwner: workerB}))
+	assert.NoError(t, err)
+	dataSample, _ = db.GetDataSample(key)
+	assert.Equal(t, workerA, dataSample.Owner, "the owner should not change before the transfer is accepted")
+	assert.Equal(t, workerB, dataSample.PendingOwner)
+
+	mockStub.Creator = workerB
+	_, err = acceptDataSampleTransfer(db, assetToArgs(inputDataSampleKeys{Keys: []string{key}}))
+	assert.Error(t, err, "the dataSample should be unlinked from the dataManagers of the previous owner")
+	assert.Contains(t, err.Error(), dataManagerKey)
+
+	mockStub.Creator = workerA
+	_, err = unlinkDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{key}, DataManagerKeys: []string{dataManagerKey}}))
+	require.NoError(t, err)
+	mockStub.Creator = workerB
+	_, err = acceptDataSampleTransfer(db, assetToArgs(inputDataSampleKeys{Keys: []string{key}}))
+	assert.NoError(t, err)
+	dataSample, _ = db.GetDataSample(key)
+	assert.Equal(t, workerB, dataSample.Owner)
+	assert.Empty(t, dataSample.PendingOwner)
+
+	// the new owner links the dataSample to its own dataManager and trains on it
+	inpDataManager := inputDataManager{Key: RandomUUID()}
+	inpDataManager.fillDefaults()
+	_, err = registerDataManager(db, assetToArgs(inpDataManager))
+	require.NoError(t, err)
+	_, err = updateDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{key}, DataManagerKeys: []string{inpDataManager.Key}}))
+	assert.NoError(t, err)
+	inpTraintuple := inputTraintuple{Key: RandomUUID(), DataManagerKey: inpDataManager.Key, DataSampleKeys: []string{key}}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.NoError(t, err)
+
+	// while the previous owner can't anymore
+	mockStub.Creator = workerA
+	_, err = updateDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{key}, DataManagerKeys: []string{dataManagerKey}}))
+	assert.Error(t, err, "only the owner can link a dataSample")
+	inpTraintuple = inputTraintuple{Key: RandomUUID(), DataSampleKeys: []string{key}}
+	inpTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(inpTraintuple))
+	assert.Error(t, err, "the dataSample is no longer associated with the dataManager of the previous owner")
+	mockStub.Creator = workerB
+
+	_, err = proposeDataSampleTransfer(db, assetToArgs(inputDataSampleTransfer{Keys: []string{key}, NewOwner: workerA}))
+	assert.NoError(t, err)
+	_, err = cancelDataSampleTransfer(db, assetToArgs(inputDataSampleKeys{Keys: []string{key}}))
+	assert.NoError(t, err)
+	mockStub.Creator = workerA
+	_, err = acceptDataSampleTransfer(db, assetToArgs(inputDataSampleKeys{Keys: []string{key}}))
+	assert.Error(t, err, "a cancelled transfer can't be accepted")
+}
+
+func TestUpdateDataSampleLinksAndTestOnly(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	key := RandomUUID()
+	inpDataSample := inputDataSample{Keys: []string{key}}
+	inpDataSample.createDefault()
+	_, err := registerDataSample(db, assetToArgs(inpDataSample))
+	require.NoError(t, err)
+
+	_, err = updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{key}, TestOnly: "true"}))
+	assert.NoError(t, err)
+	testKeys, err := getDataset(db, dataManagerKey, true)
+	assert.NoError(t, err)
+	assert.Contains(t, testKeys, key)
+	trainKeys, err := getDataset(db, dataManagerKey, false)
+	assert.NoError(t, err)
+	assert.NotContains(t, trainKeys, key)
+
+	_, err = unlinkDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{key}, DataManagerKeys: []string{dataManagerKey}}))
+	assert.NoError(t, err)
+	dataSample, _ := db.GetDataSample(key)
+	assert.Empty(t, dataSample.DataManagerKeys)
+	testKeys, _ = getDataset(db, dataManagerKey, true)
+	assert.NotContains(t, testKeys, key)
+
+	_, err = unlinkDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{key}, DataManagerKeys: []string{dataManagerKey}}))
+	assert.Error(t, err, "the dataSample is no longer associated with the dataManager")
+
+	// the train data samples are used by the registered traintuple
+	_, err = updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{trainDataSampleKey1}, TestOnly: "true"}))
+	assert.Error(t, err, "a dataSample used by a todo traintuple can't be updated")
+	assert.Contains(t, err.Error(), traintupleKey)
+	_, err = unlinkDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{trainDataSampleKey1}, DataManagerKeys: []string{dataManagerKey}}))
+	assert.Error(t, err, "a dataSample used by a todo traintuple can't be unlinked")
+
+	mockStub.Creator = workerB
+	_, err = updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{key}, TestOnly: "false"}))
+	assert.Error(t, err, "only the owner can update a dataSample")
+}
+
+func TestUpdateDataSampleInObjectiveTestDataset(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "objective")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{testDataSampleKey1}, TestOnly: "false"}))
+	assert.Error(t, err, "a dataSample of an objective test dataset can't be made available for training")
+	assert.Contains(t, err.Error(), objectiveKey)
+	_, err = unlinkDataSample(db, assetToArgs(inputUpdateDataSample{Keys: []string{testDataSampleKey1}, DataManagerKeys: []string{dataManagerKey}}))
+	assert.Error(t, err, "a dataSample of an objective test dataset can't be unlinked")
+
+	// the previous versions of the objective still refer to their test dataset
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{
+		Key:         objectiveKey,
+		TestDataset: inputDataset{DataManagerKey: dataManagerKey, DataSampleKeys: []string{testDataSampleKey2}},
+	}))
+	require.NoError(t, err)
+	_, err = updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{testDataSampleKey1}, TestOnly: "false"}))
+	assert.Error(t, err, "a dataSample of a previous objective test dataset can't be made available for training")
+	assert.Contains(t, err.Error(), "version 1")
+}
+
+func TestQueryDataSampleUsage(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	usages, _, err := queryDataSampleUsage(db, assetToArgs(inputQueryDataSampleUsage{Key: trainDataSampleKey1}))
+	assert.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, outputDataSampleUsage{
+		Key:          traintupleKey,
+		Type:         "traintuple",
+		Status:       StatusTodo,
+		OutModelKeys: []string{},
+	}, usages[0])
+
+	traintupleToDone(t, db, traintupleKey, modelKey)
+	usages, _, err = queryDataSampleUsage(db, assetToArgs(inputQueryDataSampleUsage{Key: trainDataSampleKey1}))
+	assert.NoError(t, err)
+	require.Len(t, usages, 1)
+	assert.Equal(t, StatusDone, usages[0].Status)
+	assert.Equal(t, []string{modelKey}, usages[0].OutModelKeys)
+
+	// a done traintuple doesn't prevent updating its data samples
+	_, err = updateDataSampleTestOnly(db, assetToArgs(inputUpdateDataSampleTestOnly{Keys: []string{trainDataSampleKey1}, TestOnly: "true"}))
+	assert.NoError(t, err)
+
+	usages, _, err = queryDataSampleUsage(db, assetToArgs(inputQueryDataSampleUsage{Key: testDataSampleKey1}))
+	assert.NoError(t, err)
+	assert.Len(t, usages, 0)
+}
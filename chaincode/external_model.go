@@ -0,0 +1,75 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+// Set is a method of the receiver ExternalModel. It checks the validity of
+// inputExternalModel and uses its fields to set the ExternalModel.
+func (model *ExternalModel) Set(db *LedgerDB, inp inputExternalModel) error {
+	owner, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	permissions, err := NewPermissions(db, inp.Permissions)
+	if err != nil {
+		return err
+	}
+	// the key and checksum must not already identify the out-model of a tuple
+	if err = checkOutModel(db, inp.Key, inp.Key, inp.Checksum, inp.AllowChecksumReuse); err != nil {
+		return err
+	}
+
+	model.Key = inp.Key
+	model.Name = inp.Name
+	model.AssetType = ExternalModelType
+	model.Checksum = inp.Checksum
+	model.StorageAddress = inp.StorageAddress
+	model.Owner = owner
+	model.Permissions = permissions
+	model.Metadata = inp.Metadata
+	return nil
+}
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to external models
+// -------------------------------------------------------------------------------------------
+
+// registerModel stores a model trained outside of the platform in the ledger.
+// The model has no producing tuple: its key is used directly as an in-model key.
+func registerModel(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputExternalModel{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	model := ExternalModel{}
+	err = model.Set(db, inp)
+	if err != nil {
+		return
+	}
+	err = db.Add(model.Key, model)
+	if err != nil {
+		return
+	}
+	// the model is its own producer, so that queryModel can find it
+	err = createModelIndex(db, model.Key, model.Key)
+	if err != nil {
+		return
+	}
+	err = createModelChecksumIndex(db, model.Checksum, model.Key)
+	if err != nil {
+		return
+	}
+	return outputKey{Key: model.Key}, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"chaincode/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegisterModel(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputExternalModel{
+		Key:            RandomUUID(),
+		Name:           "pretrained",
+		Checksum:       GetRandomHash(),
+		StorageAddress: "https://toto/model/pretrained",
+		Permissions:    inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}},
+	}
+	_, err := registerModel(db, assetToArgs(inp))
+	require.NoError(t, err)
+
+	_, err = registerModel(db, assetToArgs(inp))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+
+	model, err := queryModel(db, assetToArgs(inputKey{Key: inp.Key}))
+	require.NoError(t, err)
+	assert.Equal(t, workerA, model.Owner)
+	assert.Equal(t, inp.StorageAddress, model.StorageAddress)
+
+	// a registered model is usable right away as an in-model
+	traintuple := inputTraintuple{Key: RandomUUID(), InModels: []string{inp.Key}}
+	traintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(traintuple))
+	require.NoError(t, err)
+	out, err := queryTraintuple(db, assetToArgs(inputKey{Key: traintuple.Key}))
+	require.NoError(t, err)
+	assert.Equal(t, StatusTodo, out.Status)
+	require.Len(t, out.InModels, 1)
+	assert.Equal(t, inp.Checksum, out.InModels[0].Checksum)
+	assert.Equal(t, inp.StorageAddress, out.InModels[0].StorageAddress)
+
+	// its permissions apply to the workers using it
+	traintuple = inputTraintuple{
+		Key:            RandomUUID(),
+		InModels:       []string{inp.Key},
+		DataManagerKey: dataManagerKey2,
+		DataSampleKeys: []string{trainDataSampleKeyWorker2},
+	}
+	traintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(traintuple))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+
+	// or as the first in-model of a compute plan
+	cp, err := createComputePlanInternal(db, inputComputePlan{
+		Key: RandomUUID(),
+		Traintuples: []inputComputePlanTraintuple{{
+			Key:            RandomUUID(),
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{trainDataSampleKey1},
+			AlgoKey:        algoKey,
+			ID:             "first",
+			InModelsKeys:   []string{inp.Key},
+		}},
+	}, "", nil, false)
+	require.NoError(t, err)
+	require.Len(t, cp.TraintupleKeys, 1)
+	cpTraintuple, err := queryTraintuple(db, assetToArgs(inputKey{Key: cp.TraintupleKeys[0]}))
+	require.NoError(t, err)
+	assert.Equal(t, StatusTodo, cpTraintuple.Status)
+
+	// and it is the root of the lineage of the models trained from it
+	lineage, err := queryModelLineage(db, assetToArgs(inputQueryModelLineage{Key: out.Key}))
+	require.NoError(t, err)
+	require.Len(t, lineage.Nodes, 2)
+	assert.Equal(t, inp.Key, lineage.Nodes[1].Key)
+	assert.Equal(t, ExternalModelType.String(), lineage.Nodes[1].Type)
+}
+
+func TestRegisterModelOutModelConflict(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	traintuple := inputTraintuple{Key: RandomUUID()}
+	traintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(traintuple))
+	require.NoError(t, err)
+	modelKey := RandomUUID()
+	traintupleToDone(t, db, traintuple.Key, modelKey)
+	out, err := queryTraintuple(db, assetToArgs(inputKey{Key: traintuple.Key}))
+	require.NoError(t, err)
+	require.NotNil(t, out.OutModel)
+
+	inp := inputExternalModel{
+		Key:            modelKey,
+		Name:           "pretrained",
+		Checksum:       GetRandomHash(),
+		StorageAddress: "https://toto/model/pretrained",
+		Permissions:    inputPermissions{Process: inputPermission{Public: true, AuthorizedIDs: []string{}}},
+	}
+
+	// the key of an out-model cannot be registered again
+	_, err = registerModel(db, assetToArgs(inp))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+
+	// nor its checksum, unless reusing it is allowed
+	inp.Key = RandomUUID()
+	inp.Checksum = out.OutModel.Checksum
+	_, err = registerModel(db, assetToArgs(inp))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+	inp.AllowChecksumReuse = true
+	_, err = registerModel(db, assetToArgs(inp))
+	require.NoError(t, err)
+
+	// the checksum of a registered model is indexed as well
+	inp.Key = RandomUUID()
+	inp.Checksum = GetRandomHash()
+	inp.AllowChecksumReuse = false
+	_, err = registerModel(db, assetToArgs(inp))
+	require.NoError(t, err)
+	inp.Key = RandomUUID()
+	_, err = registerModel(db, assetToArgs(inp))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+}
@@ -366,6 +366,14 @@ func prettyPrintStructElements(buf io.Writer, margin string, strucType reflect.T
 				continue
 			}
 			fieldStr = fmt.Sprintf("[%s]", f.Type.Elem().Kind())
+		case reflect.Ptr:
+			if f.Type.Elem().Kind() == reflect.Struct {
+				fmt.Fprintf(buf, "%s\"%s\": (%s)", margin, f.Tag.Get("json"), f.Tag.Get("validate"))
+				prettyPrintStruct(buf, margin+" ", f.Type.Elem())
+				fmt.Fprint(buf, ",\n")
+				continue
+			}
+			fieldStr = fmt.Sprint(f.Type.Elem().Kind())
 		default:
 			fieldStr = fmt.Sprint(fieldType)
 		}
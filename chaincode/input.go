@@ -43,6 +43,20 @@ type inputObjective struct {
 	Metadata                  map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
+// inputUpdateObjective is the representation of input args to update an Objective.
+// Omitted fields are left unchanged. A new checksum comes with the storage address
+// of the new file, while a storage address alone moves the file and keeps its checksum.
+type inputUpdateObjective struct {
+	Key                       string       `validate:"required,len=36" json:"key"`
+	DescriptionChecksum       string       `validate:"omitempty,len=64,hexadecimal" json:"description_checksum"`
+	DescriptionStorageAddress string       `validate:"required_with=DescriptionChecksum,omitempty,url" json:"description_storage_address"`
+	MetricsName               string       `validate:"omitempty,gte=1,lte=100" json:"metrics_name"`
+	MetricsChecksum           string       `validate:"omitempty,len=64,hexadecimal" json:"metrics_checksum"`
+	MetricsStorageAddress     string       `validate:"required_with=MetricsChecksum,omitempty,url" json:"metrics_storage_address"`
+	TestDataset               inputDataset `validate:"omitempty" json:"test_dataset"`
+	RemoveTestDataset         bool         `json:"remove_test_dataset"`
+}
+
 // inputDataset is the representation in input args to register a dataset
 type inputDataset struct {
 	DataManagerKey string   `validate:"omitempty,len=36" json:"data_manager_key"`
@@ -83,9 +97,10 @@ type inputUpdateDataManager struct {
 
 // inputDataSample is the representation of input args to register one or more dataSample
 type inputDataSample struct {
-	Keys            []string `validate:"required,dive,len=36" json:"keys"`
-	DataManagerKeys []string `validate:"omitempty,dive,len=36" json:"data_manager_keys"`
-	TestOnly        string   `validate:"required,oneof=true false" json:"testOnly"`
+	Keys            []string          `validate:"required,dive,len=36" json:"keys"`
+	DataManagerKeys []string          `validate:"omitempty,dive,len=36" json:"data_manager_keys"`
+	TestOnly        string            `validate:"required,oneof=true false" json:"testOnly"`
+	Metadata        map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
 // inputUpdateDataSample is the representation of input args to update one or more dataSample
@@ -94,6 +109,31 @@ type inputUpdateDataSample struct {
 	DataManagerKeys []string `validate:"required,dive,len=36" json:"data_manager_keys"`
 }
 
+// inputQueryDataSampleUsage is the representation of input args to query the tuples which used a dataSample
+type inputQueryDataSampleUsage struct {
+	Key      string `validate:"required,len=36" json:"key"`
+	Bookmark string `json:"bookmark"`
+}
+
+// inputUpdateDataSampleTestOnly is the representation of input args to change
+// the testOnly flag of one or more dataSample
+type inputUpdateDataSampleTestOnly struct {
+	Keys     []string `validate:"required,unique,gt=0,dive,len=36" json:"keys"`
+	TestOnly string   `validate:"required,oneof=true false" json:"testOnly"`
+}
+
+// inputDataSampleTransfer is the representation of input args to propose the
+// ownership transfer of one or more dataSample
+type inputDataSampleTransfer struct {
+	Keys     []string `validate:"required,unique,gt=0,dive,len=36" json:"keys"`
+	NewOwner string   `validate:"required" json:"new_owner"`
+}
+
+// inputDataSampleKeys is the representation of input args targeting one or more dataSample
+type inputDataSampleKeys struct {
+	Keys []string `validate:"required,unique,gt=0,dive,len=36" json:"keys"`
+}
+
 // inputTraintuple is the representation of input args to register a Traintuple
 type inputTraintuple struct {
 	Key            string            `validate:"required,len=36" json:"key"`
@@ -104,9 +144,16 @@ type inputTraintuple struct {
 	ComputePlanKey string            `validate:"required_with=Rank" json:"compute_plan_key"`
 	Rank           string            `json:"rank"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel      bool              `json:"keep_model"`
 	Metadata       map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
+// inputUpdateKeepModel is the representation of input args to pin or unpin the out-models of a tuple
+type inputUpdateKeepModel struct {
+	Key       string `validate:"required,len=36" json:"key"`
+	KeepModel bool   `json:"keep_model"`
+}
+
 // inputTestuple is the representation of input args to register a Testtuple
 type inputTesttuple struct {
 	Key            string            `validate:"required,len=36" json:"key"`
@@ -118,6 +165,65 @@ type inputTesttuple struct {
 	TraintupleKey  string            `validate:"required,len=36" json:"traintuple_key"`
 }
 
+// inputUpdatePermissions is the representation of input args to update the permissions of an asset
+type inputUpdatePermissions struct {
+	Key         string           `validate:"required,len=36" json:"key"`
+	Permissions inputPermissions `validate:"required" json:"permissions"`
+}
+
+// inputCheckPermissions is the representation of input args to check the permissions
+// of a node on the assets of a prospective tuple
+type inputCheckPermissions struct {
+	Node                     string            `validate:"required" json:"node"`
+	Type                     string            `validate:"required,oneof=traintuple composite_traintuple aggregatetuple testtuple" json:"type"`
+	AlgoKey                  string            `validate:"omitempty,len=36" json:"algo_key"`
+	DataManagerKey           string            `validate:"omitempty,len=36" json:"data_manager_key"`
+	InModels                 []string          `validate:"omitempty,dive,len=36" json:"in_models"`
+	InHeadModelKey           string            `validate:"omitempty,len=36" json:"in_head_model_key"`
+	InTrunkModelKey          string            `validate:"omitempty,len=36" json:"in_trunk_model_key"`
+	TraintupleKey            string            `validate:"omitempty,len=36" json:"traintuple_key"`
+	Worker                   string            `json:"worker"`
+	OutTrunkModelPermissions *inputPermissions `validate:"omitempty" json:"out_trunk_model_permissions"`
+}
+
+// inputNode is the representation of input args to register or update a node
+type inputNode struct {
+	Name         string            `validate:"omitempty,lte=100" json:"name"`
+	Organization string            `validate:"omitempty,lte=100" json:"organization"`
+	Contact      string            `validate:"omitempty,lte=200" json:"contact"`
+	Metadata     map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+}
+
+// inputNodeGroup is the representation of input args to register a node group
+type inputNodeGroup struct {
+	Key     string   `validate:"required,len=36" json:"key"`
+	Name    string   `validate:"required,gte=1,lte=100" json:"name"`
+	Members []string `validate:"omitempty,unique,dive,required" json:"members"`
+}
+
+// inputUpdateNodeGroup is the representation of input args to replace the members of a node group
+type inputUpdateNodeGroup struct {
+	Key     string   `validate:"required,len=36" json:"key"`
+	Members []string `validate:"omitempty,unique,dive,required" json:"members"`
+}
+
+// inputQueryModelLineage is the representation of input args to query the upstream lineage of a model
+type inputQueryModelLineage struct {
+	Key   string `validate:"required,len=36" json:"key"`
+	Depth int    `validate:"omitempty,gte=1,lte=100" json:"depth"`
+}
+
+// inputExternalModel is the representation of input args to register a model trained outside of the platform
+type inputExternalModel struct {
+	Key                string            `validate:"required,len=36" json:"key"`
+	Name               string            `validate:"required,gte=1,lte=100" json:"name"`
+	Checksum           string            `validate:"required,len=64,hexadecimal" json:"checksum"`
+	StorageAddress     string            `validate:"required,url" json:"storage_address"`
+	Permissions        inputPermissions  `validate:"required" json:"permissions"`
+	Metadata           map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
+	AllowChecksumReuse bool              `json:"allow_checksum_reuse"`
+}
+
 type inputKey struct {
 	Key string `validate:"required,len=36" json:"key"`
 }
@@ -126,9 +232,49 @@ type inputBookmark struct {
 	Bookmark string `json:"bookmark"`
 }
 
+type inputLogProgress struct {
+	Key     string             `validate:"required,len=36" json:"key"`
+	Epoch   int                `validate:"gte=0" json:"epoch"`
+	Step    int                `validate:"gte=0" json:"step"`
+	Percent float32            `validate:"gte=0,lte=100" json:"percent"`
+	Metrics map[string]float64 `validate:"lte=20,dive,keys,lte=50,endkeys" json:"metrics"`
+}
+
+type inputReapExpiredTuples struct {
+	ComputePlanKey string   `validate:"required_without=Keys,omitempty,len=36" json:"compute_plan_key"`
+	Keys           []string `validate:"omitempty,dive,len=36" json:"keys"`
+	Requeue        bool     `json:"requeue"`
+}
+
+// inputQueryModels is the representation of input args to list the models,
+// optionally filtered
+type inputQueryModels struct {
+	ComputePlanKey string `validate:"omitempty,len=36" json:"compute_plan_key"`
+	AlgoKey        string `validate:"omitempty,len=36" json:"algo_key"`
+	Worker         string `json:"worker"`
+	Status         string `validate:"omitempty,oneof=waiting todo doing done failed canceled" json:"status"`
+	Tag            string `validate:"omitempty,lte=64" json:"tag"`
+	Bookmark       string `json:"bookmark"`
+}
+
+type inputQueryWorkerTasks struct {
+	Worker         string   `validate:"required" json:"worker"`
+	Statuses       []string `validate:"required,gt=0,dive,oneof=waiting todo doing done failed canceled" json:"statuses"`
+	ComputePlanKey string   `validate:"omitempty,len=36" json:"compute_plan_key"`
+	Bookmark       string   `json:"bookmark"`
+}
+
+type inputQueryComputePlanTuples struct {
+	Key      string `validate:"required,len=36" json:"key"`
+	Type     string `validate:"required_with=Status,omitempty,oneof=traintuple composite_traintuple aggregatetuple testtuple" json:"type"`
+	Status   string `validate:"omitempty,oneof=waiting todo doing done failed canceled" json:"status"`
+	Bookmark string `json:"bookmark"`
+}
+
 type inputLogSuccessTrain struct {
 	inputLog
-	OutModel inputKeyChecksumAddress `validate:"required" json:"out_model"`
+	OutModel           inputKeyChecksumAddress `validate:"required" json:"out_model"`
+	AllowChecksumReuse bool                    `json:"allow_checksum_reuse"`
 }
 type inputLogSuccessTest struct {
 	inputLog
@@ -187,7 +333,9 @@ type inputComputePlanTraintuple struct {
 	AlgoKey        string            `validate:"required,len=36" json:"algo_key"`
 	ID             string            `validate:"required,lte=64" json:"id"`
 	InModelsIDs    []string          `validate:"omitempty,dive,lte=64" json:"in_models_ids"`
+	InModelsKeys   []string          `validate:"omitempty,dive,len=36" json:"in_models_keys"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel      bool              `json:"keep_model"`
 	Metadata       map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
@@ -197,6 +345,7 @@ type inputComputePlanAggregatetuple struct {
 	ID          string            `validate:"required,lte=64" json:"id"`
 	InModelsIDs []string          `validate:"omitempty,dive,lte=64" json:"in_models_ids"`
 	Tag         string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel   bool              `json:"keep_model"`
 	Metadata    map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 	Worker      string            `validate:"required" json:"worker"`
 }
@@ -211,6 +360,7 @@ type inputComputePlanCompositeTraintuple struct {
 	InTrunkModelID           string            `validate:"required_with=InHeadModelID,omitempty,len=64,hexadecimal" json:"in_trunk_model_id"`
 	OutTrunkModelPermissions inputPermissions  `validate:"required" json:"out_trunk_model_permissions"`
 	Tag                      string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel                bool              `json:"keep_model"`
 	Metadata                 map[string]string `validate:"omitempty,lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
@@ -227,10 +377,13 @@ type inputComputePlanTesttuple struct {
 type inputLeaderboard struct {
 	ObjectiveKey   string `validate:"omitempty,len=36" json:"objective_key"`
 	AscendingOrder bool   `json:"ascendingOrder,required"`
+	Version        int    `validate:"gte=0" json:"version"`
 }
 
 type inputPermissions struct {
 	Process inputPermission `validate:"required" json:"process"`
+	// Download defaults to the process permission when omitted
+	Download *inputPermission `validate:"omitempty" json:"download"`
 }
 
 type inputPermission struct {
@@ -23,6 +23,7 @@ type inputAggregatetuple struct {
 	Metadata       map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 	Rank           string            `json:"rank"`
 	Tag            string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel      bool              `json:"keep_model"`
 	Worker         string            `validate:"required" json:"worker"`
 }
 
@@ -26,6 +26,7 @@ type inputCompositeTraintuple struct {
 	ComputePlanKey           string            `validate:"required_with=Rank" json:"compute_plan_key"`
 	Rank                     string            `json:"rank"`
 	Tag                      string            `validate:"omitempty,lte=64" json:"tag"`
+	KeepModel                bool              `json:"keep_model"`
 	Metadata                 map[string]string `validate:"lte=100,dive,keys,lte=50,endkeys,lte=100" json:"metadata"`
 }
 
@@ -35,6 +36,23 @@ type inputCompositeAlgo struct {
 
 type inputLogSuccessCompositeTrain struct {
 	inputLog
-	OutHeadModel  inputKeyChecksum        `validate:"required" json:"out_head_model"`
-	OutTrunkModel inputKeyChecksumAddress `validate:"required" json:"out_trunk_model"`
+	OutHeadModel       inputOutHeadModel       `validate:"required" json:"out_head_model"`
+	OutTrunkModel      inputKeyChecksumAddress `validate:"required" json:"out_trunk_model"`
+	AllowChecksumReuse bool                    `json:"allow_checksum_reuse"`
+}
+
+// inputOutHeadModel is the head out-model reported by the worker. Its storage
+// address is passed in the transient map and only revealed to the nodes allowed
+// to download the head model, it must be left empty here.
+type inputOutHeadModel struct {
+	Key            string `validate:"required,len=36" json:"key"`
+	Checksum       string `validate:"required,len=64,hexadecimal" json:"checksum"`
+	StorageAddress string `json:"storage_address"`
+}
+
+// inputGrantHeadModelDownload is the representation of input args to allow
+// nodes to download a head model
+type inputGrantHeadModelDownload struct {
+	Key           string   `validate:"required,len=36" json:"key"`
+	AuthorizedIDs []string `validate:"required,gt=0,unique" json:"authorized_ids"`
 }
@@ -34,6 +34,8 @@ const (
 	AggregatetupleType
 	TesttupleType
 	ComputePlanType
+	NodeGroupType
+	ExternalModelType
 	// when adding a new type here, don't forget to update
 	// the String() function in utils.go
 )
@@ -54,6 +56,18 @@ type Objective struct {
 	TestDataset *Dataset             `json:"test_dataset"`
 	Permissions Permissions          `json:"permissions"`
 	Metadata    map[string]string    `json:"metadata"`
+	Version     int                  `json:"version"`
+}
+
+// ObjectiveVersion is a snapshot of the fields of an objective that testtuple results
+// depend on. Certified testtuples are only comparable within the same version.
+type ObjectiveVersion struct {
+	ObjectiveKey string               `json:"objective_key"`
+	Version      int                  `json:"version"`
+	Description  *ChecksumAddress     `json:"description"`
+	Metrics      *ChecksumAddressName `json:"metrics"`
+	TestDataset  *Dataset             `json:"test_dataset"`
+	CreatedAt    int64                `json:"created_at"`
 }
 
 // DataManager is the representation of one of the elements type stored in the ledger
@@ -72,10 +86,14 @@ type DataManager struct {
 
 // DataSample is the representation of one of the element type stored in the ledger
 type DataSample struct {
-	AssetType       AssetType `json:"asset_type"`
-	DataManagerKeys []string  `json:"data_manager_keys"`
-	Owner           string    `json:"owner"`
-	TestOnly        bool      `json:"testOnly"`
+	AssetType       AssetType         `json:"asset_type"`
+	DataManagerKeys []string          `json:"data_manager_keys"`
+	Owner           string            `json:"owner"`
+	TestOnly        bool              `json:"testOnly"`
+	Metadata        map[string]string `json:"metadata"`
+	// PendingOwner is the node to which the owner proposed to transfer the
+	// data sample, until it accepts it
+	PendingOwner string `json:"pending_owner"`
 }
 
 // Algo is the representation of one of the element type stored in the ledger
@@ -110,10 +128,12 @@ type GenericTuple struct {
 	ComputePlanKey string            `json:"compute_plan_key"`
 	Creator        string            `json:"creator"`
 	Log            string            `json:"log"`
+	LogHash        string            `json:"log_hash"`
 	Metadata       map[string]string `json:"metadata"`
 	Rank           int               `json:"rank"`
 	Status         string            `json:"status"`
 	Tag            string            `json:"tag"`
+	KeepModel      bool              `json:"keep_model"`
 }
 
 // Traintuple is the representation of one the element type stored in the ledger. It describes a training task occuring on the platform
@@ -124,10 +144,12 @@ type Traintuple struct {
 	ComputePlanKey string              `json:"compute_plan_key"`
 	Creator        string              `json:"creator"`
 	Log            string              `json:"log"`
+	LogHash        string              `json:"log_hash"`
 	Metadata       map[string]string   `json:"metadata"`
 	Rank           int                 `json:"rank"`
 	Status         string              `json:"status"`
 	Tag            string              `json:"tag"`
+	KeepModel      bool                `json:"keep_model"`
 	Dataset        *Dataset            `json:"dataset"`
 	InModelKeys    []string            `json:"in_models"`
 	OutModel       *KeyChecksumAddress `json:"out_model"`
@@ -142,10 +164,12 @@ type CompositeTraintuple struct {
 	ComputePlanKey string                          `json:"compute_plan_key"`
 	Creator        string                          `json:"creator"`
 	Log            string                          `json:"log"`
+	LogHash        string                          `json:"log_hash"`
 	Metadata       map[string]string               `json:"metadata"`
 	Rank           int                             `json:"rank"`
 	Status         string                          `json:"status"`
 	Tag            string                          `json:"tag"`
+	KeepModel      bool                            `json:"keep_model"`
 	Dataset        *Dataset                        `json:"dataset"`
 	InHeadModel    string                          `json:"in_head_model"`
 	InTrunkModel   string                          `json:"in_trunk_model"`
@@ -161,10 +185,12 @@ type Aggregatetuple struct {
 	ComputePlanKey string              `json:"compute_plan_key"`
 	Creator        string              `json:"creator"`
 	Log            string              `json:"log"`
+	LogHash        string              `json:"log_hash"`
 	Metadata       map[string]string   `json:"metadata"`
 	Rank           int                 `json:"rank"`
 	Status         string              `json:"status"`
 	Tag            string              `json:"tag"`
+	KeepModel      bool                `json:"keep_model"`
 	InModelKeys    []string            `json:"in_models"`
 	OutModel       *KeyChecksumAddress `json:"out_model"`
 	Permissions    Permissions         `json:"permissions"` // TODO (aggregate): what do permissions mean here?
@@ -181,25 +207,30 @@ type CompositeTraintupleOutModel struct {
 type CompositeTraintupleOutHeadModel struct {
 	OutModel    *KeyChecksum `json:"out_model"`
 	Permissions Permissions  `json:"permissions"`
+	// StorageAddressHash is the hash of the head model storage address, which is
+	// kept in the private data collections of the nodes allowed to download it
+	StorageAddressHash string `json:"storage_address_hash"`
 }
 
 // Testtuple is the representation of one the element type stored in the ledger. It describes a training task occuring on the platform
 type Testtuple struct {
-	Key            string            `json:"key"`
-	AlgoKey        string            `json:"algo"`
-	AssetType      AssetType         `json:"asset_type"`
-	Certified      bool              `json:"certified"`
-	ComputePlanKey string            `json:"compute_plan_key"`
-	Creator        string            `json:"creator"`
-	Dataset        *TtDataset        `json:"dataset"`
-	Log            string            `json:"log"`
-	Metadata       map[string]string `json:"metadata"`
-	TraintupleKey  string            `json:"traintuple_key"`
-	ObjectiveKey   string            `json:"objective"`
-	Permissions    Permissions       `json:"permissions"`
-	Rank           int               `json:"rank"`
-	Status         string            `json:"status"`
-	Tag            string            `json:"tag"`
+	Key              string            `json:"key"`
+	AlgoKey          string            `json:"algo"`
+	AssetType        AssetType         `json:"asset_type"`
+	Certified        bool              `json:"certified"`
+	ComputePlanKey   string            `json:"compute_plan_key"`
+	Creator          string            `json:"creator"`
+	Dataset          *TtDataset        `json:"dataset"`
+	Log              string            `json:"log"`
+	LogHash          string            `json:"log_hash"`
+	Metadata         map[string]string `json:"metadata"`
+	TraintupleKey    string            `json:"traintuple_key"`
+	ObjectiveKey     string            `json:"objective"`
+	ObjectiveVersion int               `json:"objective_version"`
+	Permissions      Permissions       `json:"permissions"`
+	Rank             int               `json:"rank"`
+	Status           string            `json:"status"`
+	Tag              string            `json:"tag"`
 }
 
 // ComputePlan is the ledger's representation of a compute plan.
@@ -209,6 +240,7 @@ type ComputePlan struct {
 	AssetType               AssetType            `json:"asset_type"`
 	CleanModels             bool                 `json:"clean_models"` // whether or not to delete intermediary models
 	CompositeTraintupleKeys []string             `json:"composite_traintuple_keys"`
+	Creator                 string               `json:"creator"`
 	IDToTrainTask           map[string]TrainTask `json:"id_to_train_task"`
 	Metadata                map[string]string    `json:"metadata"`
 	State                   ComputePlanState     `json:"-"` // "-" means this field is excluded from JSON (de)serialization
@@ -234,6 +266,39 @@ type ComputePlanWorkerState struct {
 	TupleCount              int      `json:"tuple_count"` // the total number of tuples registered for this compute plan and worker
 }
 
+// TupleProgress is the last progress reported by a worker on a tuple it is
+// processing. It is stored apart from the tuple to avoid MVCC conflicts.
+type TupleProgress struct {
+	Epoch   int                `json:"epoch"`
+	Step    int                `json:"step"`
+	Percent float32            `json:"percent"`
+	Metrics map[string]float64 `json:"metrics"`
+}
+
+// TupleLease is the lease held by a worker on a tuple in the "doing" state.
+// It is stored apart from the tuple so that heartbeats don't rewrite it.
+type TupleLease struct {
+	Worker    string `json:"worker"`
+	ExpiresAt int64  `json:"expires_at"` // unix timestamp, in seconds, after which the tuple can be reaped
+}
+
+// PermissionsUpdate records a change made by its owner to the permissions of an asset.
+// The history of an asset is stored apart from it, as a list of updates.
+type PermissionsUpdate struct {
+	Previous    Permissions `json:"previous"`
+	Permissions Permissions `json:"permissions"`
+	UpdatedBy   string      `json:"updated_by"`
+	UpdatedAt   int64       `json:"updated_at"` // unix timestamp, in seconds
+}
+
+// ModelDeletion records that the owner of a model deleted its files.
+// It is stored apart from the tuple producing the model.
+type ModelDeletion struct {
+	ModelKey  string `json:"model_key"`
+	DeletedBy string `json:"deleted_by"`
+	DeletedAt int64  `json:"deleted_at"` // unix timestamp, in seconds
+}
+
 // TrainTask is represent the information for one tuple in a Compute Plan
 type TrainTask struct {
 	Depth int    `json:"depth"`
@@ -311,10 +376,43 @@ type TtDataset struct {
 type TtObjective struct {
 	Key     string           `json:"key"`
 	Metrics *ChecksumAddress `json:"metrics"`
+	Version int              `json:"version"`
 }
 
 // Node stores informations about node registered into the network,
 // would be used to list authorized nodes for permissions
 type Node struct {
-	ID string `json:"id"`
+	ID           string            `json:"id"`
+	Name         string            `json:"name"`
+	Organization string            `json:"organization"`
+	Contact      string            `json:"contact"`
+	Metadata     map[string]string `json:"metadata"`
+	RegisteredAt int64             `json:"registered_at"` // unix timestamp, in seconds
+	// Inactive is set when the node is deregistered, it can no longer be
+	// granted permissions nor be assigned aggregate tuples
+	Inactive bool `json:"inactive"`
+}
+
+// NodeGroup is a set of nodes managed by its owner. It can be referenced in the
+// authorized IDs of a permission to grant access to all its members.
+type NodeGroup struct {
+	Key       string    `json:"key"`
+	Name      string    `json:"name"`
+	AssetType AssetType `json:"asset_type"`
+	Owner     string    `json:"owner"`
+	Members   []string  `json:"members"`
+}
+
+// ExternalModel is a model trained outside of the platform and registered as
+// is. It has no producing tuple and can be used as an in-model by referencing
+// its key.
+type ExternalModel struct {
+	Key            string            `json:"key"`
+	Name           string            `json:"name"`
+	AssetType      AssetType         `json:"asset_type"`
+	Checksum       string            `json:"checksum"`
+	StorageAddress string            `json:"storage_address"`
+	Owner          string            `json:"owner"`
+	Permissions    Permissions       `json:"permissions"`
+	Metadata       map[string]string `json:"metadata"`
 }
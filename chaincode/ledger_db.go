@@ -16,7 +16,10 @@ package main
 
 import (
 	"chaincode/errors"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"net/http"
 	"strings"
 	"sync"
 
@@ -215,6 +218,93 @@ func (db *LedgerDB) GetIndexKeysWithPagination(index string, attributes []string
 	return keys, bookmark, nil
 }
 
+// ----------------------------------------------
+// Low-level functions to handle private data
+// ----------------------------------------------
+
+// getImplicitCollection returns the name of the implicit private data collection
+// of an organization. Only the peers of this organization hold its content.
+func getImplicitCollection(mspID string) string {
+	return "_implicit_org_" + mspID
+}
+
+// hashPrivateValue returns the hex encoded sha256 of a private value, as stored
+// on the public state by Fabric
+func hashPrivateValue(buff []byte) string {
+	hash := sha256.Sum256(buff)
+	return hex.EncodeToString(hash[:])
+}
+
+// PutPrivate stores an object in a private data collection and returns the hash
+// of the stored value, to be kept on the public state
+func (db *LedgerDB) PutPrivate(collection string, key string, object interface{}) (string, error) {
+	buff, _ := json.Marshal(object)
+
+	if err := db.cc.PutPrivateData(collection, key, buff); err != nil {
+		return "", err
+	}
+	return hashPrivateValue(buff), nil
+}
+
+// GetPrivate retrieves an object stored in a private data collection and set the input object value.
+// It fails if the value does not exist or if the peer is not a member of the collection.
+// Private data is not cached in the transaction state: Fabric does not allow to read
+// a private value written during the same transaction.
+func (db *LedgerDB) GetPrivate(collection string, key string, object interface{}) error {
+	buff, err := db.cc.GetPrivateData(collection, key)
+	if err != nil || buff == nil {
+		return errors.NotFound(err, "no private asset for key %s in collection %s", key, collection)
+	}
+	return json.Unmarshal(buff, &object)
+}
+
+// GetPrivateRange returns the values stored in a private data collection under the
+// keys in the range [startKey, endKey), in key order. It fails if the peer is not a
+// member of the collection.
+func (db *LedgerDB) GetPrivateRange(collection string, startKey string, endKey string) ([][]byte, error) {
+	iter, err := db.cc.GetPrivateDataByRange(collection, startKey, endKey)
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+	values := [][]byte{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, kv.Value)
+	}
+	return values, nil
+}
+
+// GetPrivateHash returns the hash of a value stored in a private data collection.
+// Unlike GetPrivate, it succeeds on any peer of the channel.
+func (db *LedgerDB) GetPrivateHash(collection string, key string) (string, error) {
+	hash, err := db.cc.GetPrivateDataHash(collection, key)
+	if err != nil || hash == nil {
+		return "", errors.NotFound(err, "no private asset for key %s in collection %s", key, collection)
+	}
+	return hex.EncodeToString(hash), nil
+}
+
+// DeletePrivate deletes a value stored in a private data collection
+func (db *LedgerDB) DeletePrivate(collection string, key string) error {
+	return db.cc.DelPrivateData(collection, key)
+}
+
+// GetTransient returns the value passed under `name` in the transient map of the
+// proposal. Transient values are not recorded in the transaction, which makes them
+// the only way to send private data to the chaincode.
+func (db *LedgerDB) GetTransient(name string) ([]byte, bool, error) {
+	transient, err := db.cc.GetTransient()
+	if err != nil {
+		return nil, false, errors.BadRequest(err, "cannot read the transient map")
+	}
+	value, ok := transient[name]
+	return value, ok, nil
+}
+
 // ----------------------------------------------
 // High-level functions
 // ----------------------------------------------
@@ -239,6 +329,11 @@ func (db *LedgerDB) GetGenericTuple(key string) (GenericTuple, error) {
 	if err != nil {
 		return asset, err
 	}
+	if asset.AssetType == ExternalModelType {
+		// a registered model is available as an in-model right away
+		asset.Status = StatusDone
+		return asset, nil
+	}
 	asset.Status, err = determineTupleStatus(db, asset.Status, asset.ComputePlanKey)
 	return asset, nil
 }
@@ -325,9 +420,31 @@ func (db *LedgerDB) GetObjective(key string) (Objective, error) {
 	if objective.AssetType != ObjectiveType {
 		return objective, errors.NotFound("objective %s not found", key)
 	}
+	// objectives registered before versioning are at their first version
+	if objective.Version == 0 {
+		objective.Version = 1
+	}
 	return objective, nil
 }
 
+// GetObjectiveVersion fetches a version of an Objective from the ledger
+func (db *LedgerDB) GetObjectiveVersion(key string, version int) (ObjectiveVersion, error) {
+	objectiveVersion := ObjectiveVersion{}
+	err := db.Get(getObjectiveVersionKey(key, version), &objectiveVersion)
+	if err == nil || errors.Wrap(err).HTTPStatusCode() != http.StatusNotFound {
+		return objectiveVersion, err
+	}
+	// objectives registered before versioning have no snapshot of their first version
+	objective, err := db.GetObjective(key)
+	if err != nil {
+		return objectiveVersion, err
+	}
+	if objective.Version != version {
+		return objectiveVersion, errors.NotFound("objective %s has no version %d", key, version)
+	}
+	return objective.getVersion(0), nil
+}
+
 // GetDataManager fetches a DataManager from the ledger using its unique key
 func (db *LedgerDB) GetDataManager(key string) (DataManager, error) {
 	dataManager := DataManager{}
@@ -419,8 +536,55 @@ func (db *LedgerDB) GetCPWorkerState(wStateKey string) (*ComputePlanWorkerState,
 	return &wState, nil
 }
 
+// GetTupleLease fetches the lease of a tuple from the chaincode db
+func (db *LedgerDB) GetTupleLease(tupleKey string) (TupleLease, error) {
+	lease := TupleLease{}
+	err := db.Get(getTupleLeaseKey(tupleKey), &lease)
+	return lease, err
+}
+
+// GetTupleProgress fetches the last progress reported on a tuple from the chaincode db.
+// It returns nil if no progress has been reported.
+func (db *LedgerDB) GetTupleProgress(tupleKey string) (*TupleProgress, error) {
+	progress := TupleProgress{}
+	err := db.Get(getTupleProgressKey(tupleKey), &progress)
+	if err != nil {
+		if errors.Wrap(err).HTTPStatusCode() == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// GetPermissionsHistory fetches the permissions updates of an asset from the chaincode db.
+// It returns an empty list if the permissions were never updated.
+func (db *LedgerDB) GetPermissionsHistory(assetKey string) ([]PermissionsUpdate, error) {
+	history := []PermissionsUpdate{}
+	err := db.Get(getPermissionsHistoryKey(assetKey), &history)
+	if err != nil && errors.Wrap(err).HTTPStatusCode() == http.StatusNotFound {
+		return history, nil
+	}
+	return history, err
+}
+
+// GetModelDeletion returns the deletion record of a model, or nil if the model
+// was not deleted
+func (db *LedgerDB) GetModelDeletion(modelKey string) (*ModelDeletion, error) {
+	deletion := ModelDeletion{}
+	err := db.Get(getModelDeletionKey(modelKey), &deletion)
+	if err != nil {
+		if errors.Wrap(err).HTTPStatusCode() == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &deletion, nil
+}
+
 // GetOutModelKeyChecksumAddress retrieves an out-Model from a tuple key.
 // In case of CompositeTraintuple it return its trunk model
+// In case of ExternalModel it returns the registered model itself
 // Return an error if the tupleKey was not found.
 func (db *LedgerDB) GetOutModelKeyChecksumAddress(tupleKey string, allowedAssetTypes []AssetType) (*KeyChecksumAddress, error) {
 	for _, assetType := range allowedAssetTypes {
@@ -441,6 +605,15 @@ func (db *LedgerDB) GetOutModelKeyChecksumAddress(tupleKey string, allowedAssetT
 			if err == nil {
 				return tuple.OutModel, nil
 			}
+		case ExternalModelType:
+			model, err := db.GetExternalModel(tupleKey)
+			if err == nil {
+				return &KeyChecksumAddress{
+					Key:            model.Key,
+					Checksum:       model.Checksum,
+					StorageAddress: model.StorageAddress,
+				}, nil
+			}
 		default:
 			return nil, errors.Internal("GetOutModelKeyChecksumAddress: Unsupported asset type %s", assetType)
 		}
@@ -472,6 +645,10 @@ func (db *LedgerDB) GetTesttuple(key string) (Testtuple, error) {
 	if testtuple.AssetType != TesttupleType {
 		return testtuple, errors.NotFound("testtuple %s not found", key)
 	}
+	// testtuples created before versioning refer to the first objective version
+	if testtuple.ObjectiveVersion == 0 {
+		testtuple.ObjectiveVersion = 1
+	}
 	testtuple.Status, err = determineTupleStatus(db, testtuple.Status, testtuple.ComputePlanKey)
 	return testtuple, err
 }
@@ -488,6 +665,30 @@ func (db *LedgerDB) GetNode(key string) (Node, error) {
 	return node, nil
 }
 
+// GetNodeGroup fetches a NodeGroup from the ledger using its unique key
+func (db *LedgerDB) GetNodeGroup(key string) (NodeGroup, error) {
+	group := NodeGroup{}
+	if err := db.Get(key, &group); err != nil {
+		return group, err
+	}
+	if group.AssetType != NodeGroupType {
+		return group, errors.NotFound("node group %s not found", key)
+	}
+	return group, nil
+}
+
+// GetExternalModel fetches an ExternalModel from the ledger using its unique key
+func (db *LedgerDB) GetExternalModel(key string) (ExternalModel, error) {
+	model := ExternalModel{}
+	if err := db.Get(key, &model); err != nil {
+		return model, err
+	}
+	if model.AssetType != ExternalModelType {
+		return model, errors.NotFound("external model %s not found", key)
+	}
+	return model, nil
+}
+
 // ----------------------------------------------
 // High-level functions for events
 // ----------------------------------------------
@@ -524,6 +725,9 @@ func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 	if db.event == nil {
 		db.event = &Event{}
 	}
+	// the event is delivered to every peer of the channel, so it is filled as
+	// an anonymous node sees it: only the addresses anyone can download are
+	// kept, the worker queries the tuple to get the others
 	switch genericTuple.AssetType {
 	case TraintupleType:
 		tuple, err := db.GetTraintuple(tupleKey)
@@ -531,7 +735,7 @@ func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 			return err
 		}
 		out := outputTraintuple{}
-		out.Fill(db, tuple)
+		out.fillAs(db, tuple, "")
 		db.event.Traintuples = append(db.event.Traintuples, out)
 	case CompositeTraintupleType:
 		tuple, err := db.GetCompositeTraintuple(tupleKey)
@@ -539,7 +743,7 @@ func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 			return err
 		}
 		out := outputCompositeTraintuple{}
-		out.Fill(db, tuple)
+		out.fillAs(db, tuple, "")
 		db.event.CompositeTraintuples = append(db.event.CompositeTraintuples, out)
 	case AggregatetupleType:
 		tuple, err := db.GetAggregatetuple(tupleKey)
@@ -547,7 +751,7 @@ func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 			return err
 		}
 		out := outputAggregatetuple{}
-		out.Fill(db, tuple)
+		out.fillAs(db, tuple, "")
 		db.event.Aggregatetuples = append(db.event.Aggregatetuples, out)
 	case TesttupleType:
 		tuple, err := db.GetTesttuple(tupleKey)
@@ -555,7 +759,7 @@ func (db *LedgerDB) AddTupleEvent(tupleKey string) error {
 			return err
 		}
 		out := outputTesttuple{}
-		out.Fill(db, tuple)
+		out.fillAs(db, tuple, "")
 		db.event.Testtuples = append(db.event.Testtuples, out)
 	}
 	return nil
@@ -94,6 +94,14 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		result, err = logFailCompositeTrain(db, args)
 	case "logFailAggregate":
 		result, err = logFailAggregate(db, args)
+	case "logProgressTest":
+		result, err = logProgressTest(db, args)
+	case "logProgressTrain":
+		result, err = logProgressTrain(db, args)
+	case "logProgressCompositeTrain":
+		result, err = logProgressCompositeTrain(db, args)
+	case "logProgressAggregate":
+		result, err = logProgressAggregate(db, args)
 	case "logStartTest":
 		result, err = logStartTest(db, args)
 	case "logStartTrain":
@@ -102,6 +110,8 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		result, err = logStartCompositeTrain(db, args)
 	case "logStartAggregate":
 		result, err = logStartAggregate(db, args)
+	case "heartbeatTuple":
+		result, err = heartbeatTuple(db, args)
 	case "logSuccessTest":
 		result, err = logSuccessTest(db, args)
 	case "logSuccessTrain":
@@ -130,6 +140,9 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 	case "queryDataManagers":
 		result, bookmark, err = queryDataManagers(db, args)
 		hasBookmark = true
+	case "queryDataSampleUsage":
+		result, bookmark, err = queryDataSampleUsage(db, args)
+		hasBookmark = true
 	case "queryDataSamples":
 		result, bookmark, err = queryDataSamples(db, args)
 		hasBookmark = true
@@ -141,16 +154,27 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		result, err = queryModel(db, args)
 	case "queryModelDetails":
 		result, err = queryModelDetails(db, args)
+	case "queryWorkerTasks":
+		result, bookmark, err = queryWorkerTasks(db, args)
+		hasBookmark = true
+	case "queryModelLineage":
+		result, err = queryModelLineage(db, args)
 	case "queryModels":
 		result, bookmark, err = queryModels(db, args)
 		hasBookmark = true
+	case "checkPermissions":
+		result, err = checkPermissions(db, args)
 	case "queryObjective":
 		result, err = queryObjective(db, args)
 	case "queryObjectiveLeaderboard":
 		result, err = queryObjectiveLeaderboard(db, args)
+	case "queryObjectiveVersions":
+		result, err = queryObjectiveVersions(db, args)
 	case "queryObjectives":
 		result, bookmark, err = queryObjectives(db, args)
 		hasBookmark = true
+	case "queryPermissionsHistory":
+		result, err = queryPermissionsHistory(db, args)
 	case "queryTesttuple":
 		result, err = queryTesttuple(db, args)
 	case "queryTesttuples":
@@ -158,6 +182,8 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		hasBookmark = true
 	case "queryTraintuple":
 		result, err = queryTraintuple(db, args)
+	case "queryTupleLog":
+		result, err = queryTupleLog(db, args)
 	case "queryCompositeTraintuple":
 		result, err = queryCompositeTraintuple(db, args)
 	case "queryAggregatetuple":
@@ -176,6 +202,13 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 	case "queryComputePlans":
 		result, bookmark, err = queryComputePlans(db, args)
 		hasBookmark = true
+	case "queryComputePlanProgress":
+		result, err = queryComputePlanProgress(db, args)
+	case "queryComputePlanTuples":
+		result, bookmark, err = queryComputePlanTuples(db, args)
+		hasBookmark = true
+	case "reapExpiredTuples":
+		result, err = reapExpiredTuples(db, args)
 	case "registerAlgo":
 		result, err = registerAlgo(db, args)
 	case "registerCompositeAlgo":
@@ -186,18 +219,52 @@ func (t *SubstraChaincode) Invoke(stub shim.ChaincodeStubInterface) peer.Respons
 		result, err = registerDataManager(db, args)
 	case "registerDataSample":
 		result, err = registerDataSample(db, args)
+	case "registerModel":
+		result, err = registerModel(db, args)
+	case "logModelDeleted":
+		result, err = logModelDeleted(db, args)
+	case "updateKeepModel":
+		result, err = updateKeepModel(db, args)
+	case "grantHeadModelDownload":
+		result, err = grantHeadModelDownload(db, args)
 	case "registerObjective":
 		result, err = registerObjective(db, args)
+	case "updateObjective":
+		result, err = updateObjective(db, args)
 	case "updateComputePlan":
 		result, err = updateComputePlan(db, args)
 	case "updateDataManager":
 		result, err = updateDataManager(db, args)
 	case "updateDataSample":
 		result, err = updateDataSample(db, args)
+	case "unlinkDataSample":
+		result, err = unlinkDataSample(db, args)
+	case "updateDataSampleTestOnly":
+		result, err = updateDataSampleTestOnly(db, args)
+	case "proposeDataSampleTransfer":
+		result, err = proposeDataSampleTransfer(db, args)
+	case "acceptDataSampleTransfer":
+		result, err = acceptDataSampleTransfer(db, args)
+	case "cancelDataSampleTransfer":
+		result, err = cancelDataSampleTransfer(db, args)
+	case "updatePermissions":
+		result, err = updatePermissions(db, args)
 	case "registerNode":
 		result, err = registerNode(db, args)
+	case "updateNode":
+		result, err = updateNode(db, args)
+	case "deregisterNode":
+		result, err = deregisterNode(db, args)
 	case "queryNodes":
 		result, err = queryNodes(db, args)
+	case "registerNodeGroup":
+		result, err = registerNodeGroup(db, args)
+	case "updateNodeGroup":
+		result, err = updateNodeGroup(db, args)
+	case "queryNodeGroup":
+		result, err = queryNodeGroup(db, args)
+	case "queryNodeGroups":
+		result, err = queryNodeGroups(db, args)
 	default:
 		err = errors.BadRequest("function \"%s\" not implemented", fn)
 	}
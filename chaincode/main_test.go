@@ -63,6 +63,7 @@ const headModelChecksum = modelChecksum
 const trunkModelKey = "ccdbb7c3-1f62-244c-0f3a-761cc1688042"
 const trunkModelChecksum = "ccdbb7c31f62244c0f3a761cc168804227115793d01c270021fe3f7935482ecc"
 const trunkModelAddress = "https://substrabac/model/titi"
+const headModelAddress = modelAddress
 const workerA = "SampleOrg"
 const workerB = "SampleOrgB"
 const workerC = "SampleOrgC"
@@ -347,16 +348,9 @@ func TestQueryEmptyResponse(t *testing.T) {
 			args := [][]byte{[]byte(contractName)}
 			resp := mockStub.MockInvoke(args)
 
-			expectedBookmark := ""
-			if contractName == "queryModels" {
-				// special case for queryModels: we return a map instead of a string
-				expectedBookmarkBytes, _ := json.Marshal(queryModelsBookmarks{})
-				expectedBookmark = string(expectedBookmarkBytes)
-			}
-
 			expectedResult := map[string]interface{}{
 				"results":  make([]string, 0),
-				"bookmark": expectedBookmark}
+				"bookmark": ""}
 
 			expectedPayload, _ := json.Marshal(expectedResult)
 			assert.Equal(t, expectedPayload, resp.Payload, "payload is not an empty list")
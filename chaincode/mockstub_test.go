@@ -16,6 +16,8 @@ package main
 
 import (
 	"container/list"
+	"crypto/sha256"
+	"sort"
 	"strings"
 	"unicode/utf8"
 
@@ -77,6 +79,9 @@ type MockStub struct {
 
 	PvtState map[string]map[string][]byte
 
+	// stores the transient map of the proposal
+	TransientMap map[string][]byte
+
 	// stores per-key endorsement policy, first map index is the collection, second map index is the key
 	EndorsementPolicies map[string]map[string][]byte
 
@@ -200,15 +205,25 @@ func (stub *MockStub) PutPrivateData(collection string, key string, value []byte
 }
 
 func (stub *MockStub) DelPrivateData(collection string, key string) error {
-	return errors.New("Not Implemented")
+	if m, in := stub.PvtState[collection]; in {
+		delete(m, key)
+	}
+	return nil
 }
 
 func (stub *MockStub) GetPrivateDataByRange(collection, startKey, endKey string) (shim.StateQueryIteratorInterface, error) {
-	return nil, errors.New("Not Implemented")
+	if err := validateSimpleKeys(startKey, endKey); err != nil {
+		return nil, err
+	}
+	return NewMockPrivateRangeQueryIterator(stub, collection, startKey, endKey), nil
 }
 
 func (stub *MockStub) GetPrivateDataByPartialCompositeKey(collection, objectType string, attributes []string) (shim.StateQueryIteratorInterface, error) {
-	return nil, errors.New("Not Implemented")
+	partialCompositeKey, err := stub.CreateCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	return NewMockPrivateRangeQueryIterator(stub, collection, partialCompositeKey, partialCompositeKey+string(maxUnicodeRuneValue)), nil
 }
 
 func (stub *MockStub) GetPrivateDataQueryResult(collection, query string) (shim.StateQueryIteratorInterface, error) {
@@ -407,9 +422,8 @@ func (stub *MockStub) GetCreator() ([]byte, error) {
 	return proto.Marshal(sid)
 }
 
-// Not implemented
 func (stub *MockStub) GetTransient() (map[string][]byte, error) {
-	return nil, nil
+	return stub.TransientMap, nil
 }
 
 // Not implemented
@@ -431,9 +445,14 @@ func (stub *MockStub) GetArgsSlice() ([]byte, error) {
 	return nil, nil
 }
 
-// Not implemented
+// GetPrivateDataHash returns the sha256 of a private value, as any peer of the channel would
 func (stub *MockStub) GetPrivateDataHash(collection, key string) ([]byte, error) {
-	return nil, nil
+	value, err := stub.GetPrivateData(collection, key)
+	if err != nil || value == nil {
+		return nil, err
+	}
+	hash := sha256.Sum256(value)
+	return hash[:], nil
 }
 
 func (stub *MockStub) setTxTimestamp(time *timestamp.Timestamp) {
@@ -542,18 +561,19 @@ func (iter *MockStateRangeQueryIterator) HasNext() bool {
 		if iter.StartKey == "" && iter.EndKey == "" {
 			return true
 		}
+		comp1 := strings.Compare(current.Value.(string), iter.StartKey)
+		comp2 := strings.Compare(current.Value.(string), iter.EndKey)
+		// past the end of the range, even on a full page: there is no next page
+		if comp1 >= 0 && comp2 >= 0 {
+			iter.Metadata.Bookmark = ""
+			return false
+		}
 		// iterator has already yielded enough results
 		if iter.IsPaginated && iter.Metadata.FetchedRecordsCount == iter.PageSize {
 			return false
 		}
-		comp1 := strings.Compare(current.Value.(string), iter.StartKey)
-		comp2 := strings.Compare(current.Value.(string), iter.EndKey)
 		if comp1 >= 0 {
-			if comp2 < 0 {
-				return true
-			}
-			iter.Metadata.Bookmark = ""
-			return false
+			return true
 		}
 		current = current.Next()
 	}
@@ -632,6 +652,57 @@ func NewMockStateRangeQueryIterator(stub *MockStub, startKey string, endKey stri
 	return iter
 }
 
+/*****************************
+ Private Data Range Query Iterator
+*****************************/
+
+// MockPrivateRangeQueryIterator iterates over a snapshot of the keys of a private
+// data collection taken when the query is made
+type MockPrivateRangeQueryIterator struct {
+	Closed     bool
+	Stub       *MockStub
+	Collection string
+	Keys       []string
+}
+
+// HasNext returns true if the range query iterator contains additional keys
+// and values.
+func (iter *MockPrivateRangeQueryIterator) HasNext() bool {
+	return !iter.Closed && len(iter.Keys) > 0
+}
+
+// Next returns the next key and value in the range query iterator.
+func (iter *MockPrivateRangeQueryIterator) Next() (*queryresult.KV, error) {
+	if !iter.HasNext() {
+		return nil, errors.New("MockPrivateRangeQueryIterator.Next() called when it does not HaveNext()")
+	}
+	key := iter.Keys[0]
+	iter.Keys = iter.Keys[1:]
+	value, err := iter.Stub.GetPrivateData(iter.Collection, key)
+	return &queryresult.KV{Key: key, Value: value}, err
+}
+
+// Close closes the range query iterator.
+func (iter *MockPrivateRangeQueryIterator) Close() error {
+	if iter.Closed {
+		return errors.New("MockPrivateRangeQueryIterator.Close() called after Close()")
+	}
+	iter.Closed = true
+	return nil
+}
+
+func NewMockPrivateRangeQueryIterator(stub *MockStub, collection string, startKey string, endKey string) *MockPrivateRangeQueryIterator {
+	iter := &MockPrivateRangeQueryIterator{Stub: stub, Collection: collection}
+	for key := range stub.PvtState[collection] {
+		// an empty start or end key leaves the range open on that side
+		if (startKey == "" || key >= startKey) && (endKey == "" || key < endKey) {
+			iter.Keys = append(iter.Keys, key)
+		}
+	}
+	sort.Strings(iter.Keys)
+	return iter
+}
+
 func getBytes(function string, args []string) [][]byte {
 	bytes := make([][]byte, 0, len(args)+1)
 	bytes = append(bytes, []byte(function))
@@ -167,19 +167,28 @@ func trainStart(db *LedgerDB, tupleType AssetType, tupleKey string) (interface{}
 }
 
 func trainSuccess(db *LedgerDB, tupleType AssetType, tupleKey string) (interface{}, error) {
+	// both parents are done in the same test: each one reports its own out-models
 	switch tupleType {
 	case TraintupleType:
 		successParent1 := inputLogSuccessTrain{}
+		successParent1.OutModel.Key = RandomUUID()
+		successParent1.OutModel.Checksum = GetRandomHash()
 		successParent1.fillDefaults()
 		successParent1.Key = tupleKey
 		return logSuccessTrain(db, assetToArgs(successParent1))
 	case CompositeTraintupleType:
 		successParent1 := inputLogSuccessCompositeTrain{}
+		successParent1.OutHeadModel.Key = RandomUUID()
+		successParent1.OutHeadModel.Checksum = GetRandomHash()
+		successParent1.OutTrunkModel.Key = RandomUUID()
+		successParent1.OutTrunkModel.Checksum = GetRandomHash()
 		successParent1.fillDefaults()
 		successParent1.Key = tupleKey
 		return logSuccessCompositeTrain(db, assetToArgs(successParent1))
 	case AggregatetupleType:
 		successParent1 := inputLogSuccessTrain{}
+		successParent1.OutModel.Key = RandomUUID()
+		successParent1.OutModel.Checksum = GetRandomHash()
 		successParent1.fillDefaults()
 		successParent1.Key = tupleKey
 		return logSuccessAggregate(db, assetToArgs(successParent1))
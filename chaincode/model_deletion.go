@@ -0,0 +1,233 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+)
+
+// -------------------------------------------------------------------------------------------
+// Smart contracts related to model deletion
+// -------------------------------------------------------------------------------------------
+
+// logModelDeleted records that the owner of a model deleted its files.
+// A deleted model can't be used as an in-model anymore.
+func logModelDeleted(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	keys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", inp.Key})
+	if err != nil {
+		return
+	}
+	if len(keys) == 0 {
+		return resp, errors.NotFound("Could not find a model for key %s", inp.Key)
+	}
+	owner, err := getModelOwner(db, keys[0])
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != owner {
+		return resp, errors.Forbidden("%s is not allowed to log the deletion of model %s", txCreator, inp.Key)
+	}
+	deletion, err := db.GetModelDeletion(inp.Key)
+	if err != nil {
+		return
+	}
+	if deletion != nil {
+		return resp, errors.Conflict("model %s is already deleted", inp.Key).WithKey(inp.Key)
+	}
+	head, err := isHeadModel(db, keys[0], inp.Key)
+	if err != nil {
+		return
+	}
+	if err = checkModelNotNeeded(db, keys[0], head); err != nil {
+		return
+	}
+	now, err := GetTxTime(db.cc)
+	if err != nil {
+		return
+	}
+	deletion = &ModelDeletion{
+		ModelKey:  inp.Key,
+		DeletedBy: txCreator,
+		DeletedAt: now.Unix(),
+	}
+	if err = db.Put(getModelDeletionKey(inp.Key), deletion); err != nil {
+		return
+	}
+	return outputKey{Key: inp.Key}, nil
+}
+
+// getModelOwner returns the node holding the out-models of a tuple, or the
+// owner of an external model
+func getModelOwner(db *LedgerDB, tupleKey string) (string, error) {
+	assetType, err := db.GetAssetType(tupleKey)
+	if err != nil {
+		return "", err
+	}
+	switch assetType {
+	case TraintupleType:
+		tuple, err := db.GetTraintuple(tupleKey)
+		return tuple.Dataset.Worker, err
+	case CompositeTraintupleType:
+		tuple, err := db.GetCompositeTraintuple(tupleKey)
+		return tuple.Dataset.Worker, err
+	case AggregatetupleType:
+		tuple, err := db.GetAggregatetuple(tupleKey)
+		return tuple.Worker, err
+	case ExternalModelType:
+		model, err := db.GetExternalModel(tupleKey)
+		return model.Owner, err
+	default:
+		return "", errors.Internal("getModelOwner: unsupported asset type %s", assetType)
+	}
+}
+
+// isHeadModel returns true if the model is the head out-model of a composite traintuple
+func isHeadModel(db *LedgerDB, tupleKey string, modelKey string) (bool, error) {
+	assetType, err := db.GetAssetType(tupleKey)
+	if err != nil || assetType != CompositeTraintupleType {
+		return false, err
+	}
+	headModelKey, err := getTupleOutModelKey(db, tupleKey, true)
+	return headModelKey == modelKey, err
+}
+
+// checkModelNotNeeded returns a BadRequest error if a tuple which is not over
+// still needs the out-model of the given tuple as an in-model, or to be tested.
+// For composite traintuples, the head or trunk out-model is selected by the
+// head argument.
+func checkModelNotNeeded(db *LedgerDB, tupleKey string, head bool) error {
+	childrenKeys, err := db.GetIndexKeys("tuple~inModel~key", []string{"tuple", tupleKey})
+	if err != nil {
+		return err
+	}
+	testtupleKeys, err := db.GetIndexKeys("testtuple~traintuple~certified~key", []string{"testtuple", tupleKey})
+	if err != nil {
+		return err
+	}
+	for _, childKey := range append(childrenKeys, testtupleKeys...) {
+		child, err := db.GetGenericTuple(childKey)
+		if err != nil {
+			return err
+		}
+		if !stringInSlice(child.Status, []string{StatusWaiting, StatusTodo, StatusDoing}) {
+			continue
+		}
+		switch child.AssetType {
+		case TesttupleType:
+			// a testtuple uses all the out-models of the tuple it tests
+		case CompositeTraintupleType:
+			// a composite traintuple uses the head out-model of its head
+			// in-model and the trunk out-model of its trunk in-model
+			composite, err := db.GetCompositeTraintuple(childKey)
+			if err != nil {
+				return err
+			}
+			if head && composite.InHeadModel != tupleKey || !head && composite.InTrunkModel != tupleKey {
+				continue
+			}
+		default:
+			// only composite traintuples can use a head out-model
+			if head {
+				continue
+			}
+		}
+		return errors.BadRequest("the model of tuple %s is still needed by tuple %s with status %s", tupleKey, childKey, child.Status)
+	}
+	return nil
+}
+
+// getTupleOutModelKey returns the key of the out-model of a tuple, or an empty
+// string if it wasn't produced yet. For composite traintuples, the head or
+// trunk out-model is selected by the head argument.
+func getTupleOutModelKey(db *LedgerDB, tupleKey string, head bool) (string, error) {
+	if head {
+		outModel, err := db.GetOutHeadModelKeyChecksum(tupleKey)
+		if err != nil || outModel == nil {
+			return "", err
+		}
+		return outModel.Key, nil
+	}
+	outModel, err := db.GetOutModelKeyChecksumAddress(tupleKey, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType})
+	if err != nil || outModel == nil {
+		return "", err
+	}
+	return outModel.Key, nil
+}
+
+// getTupleModelDeletions returns the deletion records of the out-models of a tuple
+func getTupleModelDeletions(db *LedgerDB, tupleKey string) ([]ModelDeletion, error) {
+	deletions := []ModelDeletion{}
+	assetType, err := db.GetAssetType(tupleKey)
+	if err != nil {
+		return deletions, err
+	}
+	heads := []bool{false}
+	switch assetType {
+	case TraintupleType, AggregatetupleType, ExternalModelType:
+	case CompositeTraintupleType:
+		heads = append(heads, true)
+	default:
+		return deletions, nil
+	}
+	for _, head := range heads {
+		modelKey, err := getTupleOutModelKey(db, tupleKey, head)
+		if err != nil {
+			return deletions, err
+		}
+		if modelKey == "" {
+			continue
+		}
+		deletion, err := db.GetModelDeletion(modelKey)
+		if err != nil {
+			return deletions, err
+		}
+		if deletion != nil {
+			deletions = append(deletions, *deletion)
+		}
+	}
+	return deletions, nil
+}
+
+// checkInModelNotDeleted returns a BadRequest error if the out-model of the
+// given tuple was deleted by its owner
+func checkInModelNotDeleted(db *LedgerDB, tupleKey string, head bool) error {
+	modelKey, err := getTupleOutModelKey(db, tupleKey, head)
+	if err != nil || modelKey == "" {
+		return err
+	}
+	deletion, err := db.GetModelDeletion(modelKey)
+	if err != nil {
+		return err
+	}
+	if deletion != nil {
+		return errors.BadRequest("in-model %s of tuple %s was deleted by %s", modelKey, tupleKey, deletion.DeletedBy)
+	}
+	return nil
+}
+
+// getModelDeletionKey returns the deletion record key for a given model
+func getModelDeletionKey(modelKey string) string {
+	return fmt.Sprintf("model~%v~deleted", modelKey)
+}
@@ -0,0 +1,132 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"chaincode/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogModelDeleted(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	parent := inputTraintuple{Key: RandomUUID()}
+	parent.createDefault()
+	_, err := createTraintuple(db, assetToArgs(parent))
+	require.NoError(t, err)
+	traintupleToDone(t, db, parent.Key, modelKey)
+
+	model, err := queryModel(db, assetToArgs(inputKey{Key: modelKey}))
+	require.NoError(t, err)
+	assert.False(t, model.Deleted)
+	assert.NotEmpty(t, model.StorageAddress)
+
+	// only the node holding the model can log its deletion
+	mockStub.Creator = workerB
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+
+	mockStub.Creator = workerA
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	require.NoError(t, err)
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+
+	model, err = queryModel(db, assetToArgs(inputKey{Key: modelKey}))
+	require.NoError(t, err)
+	assert.True(t, model.Deleted)
+	assert.NotZero(t, model.DeletedAt)
+	assert.Empty(t, model.StorageAddress)
+
+	details, err := queryModelDetails(db, assetToArgs(inputKey{Key: parent.Key}))
+	require.NoError(t, err)
+	require.Len(t, details.DeletedModels, 1)
+	assert.Equal(t, modelKey, details.DeletedModels[0].ModelKey)
+	assert.Equal(t, workerA, details.DeletedModels[0].DeletedBy)
+
+	// a deleted model can't be used as an in-model anymore
+	child := inputTraintuple{Key: RandomUUID(), InModels: []string{parent.Key}}
+	child.createDefault()
+	_, err = createTraintuple(db, assetToArgs(child))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+
+	aggregate := inputAggregatetuple{Key: RandomUUID(), InModels: []string{parent.Key}}
+	aggregate.createDefault()
+	_, err = createAggregatetuple(db, assetToArgs(aggregate))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestLogModelDeletedStillNeeded(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	parent := inputTraintuple{Key: RandomUUID()}
+	parent.createDefault()
+	_, err := createTraintuple(db, assetToArgs(parent))
+	require.NoError(t, err)
+	traintupleToDone(t, db, parent.Key, modelKey)
+
+	child := inputTraintuple{Key: RandomUUID(), InModels: []string{parent.Key}}
+	child.createDefault()
+	_, err = createTraintuple(db, assetToArgs(child))
+	require.NoError(t, err)
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the model is needed by a todo traintuple")
+	traintupleToDone(t, db, child.Key, RandomUUID())
+
+	testtuple := inputTesttuple{Key: RandomUUID(), TraintupleKey: parent.Key}
+	testtuple.createDefault()
+	_, err = createTesttuple(db, assetToArgs(testtuple))
+	require.NoError(t, err)
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the model is needed by a todo testtuple")
+	testtupleToDone(t, db, testtuple.Key)
+
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: modelKey}))
+	assert.NoError(t, err, "the model is no longer needed")
+
+	// a deleted model can't be tested anymore
+	testtuple = inputTesttuple{Key: RandomUUID(), TraintupleKey: parent.Key}
+	testtuple.createDefault()
+	_, err = createTesttuple(db, assetToArgs(testtuple))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+
+	// a composite traintuple only needs the head out-model of its head in-model
+	composite := inputCompositeTraintuple{Key: RandomUUID()}
+	composite.createDefault()
+	_, err = createCompositeTraintuple(db, assetToArgs(composite))
+	require.NoError(t, err)
+	headKey, trunkKey := RandomUUID(), RandomUUID()
+	compositeToDone(t, mockStub, workerA, db, composite.Key, headKey, trunkKey)
+
+	childComposite := inputCompositeTraintuple{Key: RandomUUID(), InHeadModelKey: composite.Key, InTrunkModelKey: child.Key}
+	childComposite.createDefault()
+	_, err = createCompositeTraintuple(db, assetToArgs(childComposite))
+	require.NoError(t, err)
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: headKey}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the head model is needed by a todo composite traintuple")
+	_, err = logModelDeleted(db, assetToArgs(inputKey{Key: trunkKey}))
+	assert.NoError(t, err, "the trunk model isn't used by the composite traintuple")
+}
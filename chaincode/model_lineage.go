@@ -0,0 +1,140 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "chaincode/errors"
+
+// DefaultModelLineageDepth is the number of upstream levels returned by
+// queryModelLineage when no depth is given
+const DefaultModelLineageDepth = 10
+
+// queryModelLineage returns the upstream DAG of the tuple producing a model:
+// its in-models, their own in-models, and so on, up to the requested depth.
+// The key can be either the one of the tuple or of one of its out-models.
+// Each tuple appears once, at the smallest depth it is reached.
+func queryModelLineage(db *LedgerDB, args []string) (out outputModelLineage, err error) {
+	inp := inputQueryModelLineage{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	maxDepth := inp.Depth
+	if maxDepth == 0 {
+		maxDepth = DefaultModelLineageDepth
+	}
+	tupleKey, err := getModelTupleKey(db, inp.Key)
+	if err != nil {
+		return
+	}
+
+	out = outputModelLineage{Key: tupleKey, Nodes: []outputModelLineageNode{}}
+	visited := map[string]bool{tupleKey: true}
+	level := []string{tupleKey}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth > maxDepth {
+			out.Truncated = true
+			break
+		}
+		nextLevel := []string{}
+		for _, key := range level {
+			node, err := getModelLineageNode(db, key, depth)
+			if err != nil {
+				return out, err
+			}
+			out.Nodes = append(out.Nodes, node)
+			for _, inModelKey := range node.InModelKeys {
+				if !visited[inModelKey] {
+					visited[inModelKey] = true
+					nextLevel = append(nextLevel, inModelKey)
+				}
+			}
+		}
+		level = nextLevel
+	}
+	return
+}
+
+// getModelTupleKey returns the key of the tuple producing a model, given either
+// the tuple key or the model key
+func getModelTupleKey(db *LedgerDB, key string) (string, error) {
+	keys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", key})
+	if err != nil {
+		return "", err
+	}
+	if len(keys) > 0 {
+		return keys[0], nil
+	}
+	assetType, err := db.GetAssetType(key)
+	if err != nil {
+		return "", err
+	}
+	if !typeInSlice(assetType, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType}) {
+		return "", errors.BadRequest("key %s is neither a model nor a tuple producing a model", key)
+	}
+	return key, nil
+}
+
+func getModelLineageNode(db *LedgerDB, key string, depth int) (outputModelLineageNode, error) {
+	node := outputModelLineageNode{Key: key, Depth: depth, InModelKeys: []string{}}
+	assetType, err := db.GetAssetType(key)
+	if err != nil {
+		return node, err
+	}
+	node.Type = assetType.String()
+	switch assetType {
+	case TraintupleType:
+		tuple, err := db.GetTraintuple(key)
+		if err != nil {
+			return node, err
+		}
+		node.AlgoKey = tuple.AlgoKey
+		node.DataManagerKey = tuple.Dataset.DataManagerKey
+		node.DataSampleCount = len(tuple.Dataset.DataSampleKeys)
+		node.Worker = tuple.Dataset.Worker
+		node.InModelKeys = append(node.InModelKeys, tuple.InModelKeys...)
+	case CompositeTraintupleType:
+		tuple, err := db.GetCompositeTraintuple(key)
+		if err != nil {
+			return node, err
+		}
+		node.AlgoKey = tuple.AlgoKey
+		node.DataManagerKey = tuple.Dataset.DataManagerKey
+		node.DataSampleCount = len(tuple.Dataset.DataSampleKeys)
+		node.Worker = tuple.Dataset.Worker
+		for _, inModelKey := range []string{tuple.InHeadModel, tuple.InTrunkModel} {
+			if inModelKey != "" {
+				node.InModelKeys = append(node.InModelKeys, inModelKey)
+			}
+		}
+	case AggregatetupleType:
+		tuple, err := db.GetAggregatetuple(key)
+		if err != nil {
+			return node, err
+		}
+		node.AlgoKey = tuple.AlgoKey
+		node.Worker = tuple.Worker
+		node.InModelKeys = append(node.InModelKeys, tuple.InModelKeys...)
+	case ExternalModelType:
+		// a registered model has no upstream tuple
+		model, err := db.GetExternalModel(key)
+		if err != nil {
+			return node, err
+		}
+		node.Worker = model.Owner
+	default:
+		return node, errors.Internal("queryModelLineage: unsupported in-model type %s for key %s", assetType, key)
+	}
+	return node, nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryModelLineage(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+	step1A, step1B := out.CompositeTraintupleKeys[0], out.CompositeTraintupleKeys[1]
+	step2 := out.AggregatetupleKeys[0]
+	step3A := out.CompositeTraintupleKeys[2]
+
+	lineage, err := queryModelLineage(db, assetToArgs(inputQueryModelLineage{Key: step3A}))
+	assert.NoError(t, err)
+	assert.False(t, lineage.Truncated)
+	require.Len(t, lineage.Nodes, 4, "step 1A is reached twice but appears once")
+	assert.Equal(t, outputModelLineageNode{
+		Key:             step3A,
+		Type:            "composite_traintuple",
+		AlgoKey:         compositeAlgoKey,
+		DataManagerKey:  dataManagerKey,
+		DataSampleCount: 1,
+		Worker:          workerA,
+		InModelKeys:     []string{step1A, step2},
+	}, lineage.Nodes[0])
+	assert.Equal(t, step1A, lineage.Nodes[1].Key)
+	assert.Equal(t, 1, lineage.Nodes[1].Depth)
+	assert.Equal(t, outputModelLineageNode{
+		Key:         step2,
+		Type:        "aggregatetuple",
+		Depth:       1,
+		AlgoKey:     aggregateAlgoKey,
+		Worker:      workerC,
+		InModelKeys: []string{step1A, step1B},
+	}, lineage.Nodes[2])
+	assert.Equal(t, step1B, lineage.Nodes[3].Key)
+	assert.Equal(t, 2, lineage.Nodes[3].Depth)
+
+	lineage, err = queryModelLineage(db, assetToArgs(inputQueryModelLineage{Key: step3A, Depth: 1}))
+	assert.NoError(t, err)
+	assert.True(t, lineage.Truncated)
+	assert.Len(t, lineage.Nodes, 3)
+
+	_, err = queryModelLineage(db, assetToArgs(inputQueryModelLineage{Key: out.TesttupleKeys[0]}))
+	assert.Error(t, err, "a testtuple doesn't produce a model")
+}
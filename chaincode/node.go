@@ -18,25 +18,51 @@ import (
 	"chaincode/errors"
 )
 
+// registerNode registers the transaction creator as a node. The node details
+// are optional. Registering an existing node applies the details given, if
+// any, and reactivates the node if it was deregistered.
 func registerNode(db *LedgerDB, args []string) (Node, error) {
+	inp := inputNode{}
+	hasInput := !isEmptyArgs(args)
+	if hasInput {
+		if err := AssetFromJSON(args, &inp); err != nil {
+			return Node{}, err
+		}
+	}
+
 	txCreator, err := GetTxCreator(db.cc)
 	if err != nil {
 		return Node{}, err
 	}
 
-	node := Node{}
-	node.ID = txCreator
-
 	// Not using db.Add because we need to handle conflict as silent event without errors
-	exists, err := db.KeyExists(node.ID)
+	exists, err := db.KeyExists(txCreator)
 	if err != nil {
 		return Node{}, err
 	}
 
 	if exists {
-		return node, nil
+		node, err := db.GetNode(txCreator)
+		if err != nil {
+			return Node{}, err
+		}
+		if !hasInput && !node.Inactive {
+			return node, nil
+		}
+		if hasInput {
+			node.setFromInput(inp)
+		}
+		node.Inactive = false
+		return node, db.Put(node.ID, node)
 	}
 
+	now, err := GetTxTime(db.cc)
+	if err != nil {
+		return Node{}, err
+	}
+	node := Node{ID: txCreator, RegisteredAt: now.Unix()}
+	node.setFromInput(inp)
+
 	err = db.Put(node.ID, node)
 	if err != nil {
 		return Node{}, err
@@ -50,6 +76,58 @@ func registerNode(db *LedgerDB, args []string) (Node, error) {
 	return node, nil
 }
 
+// updateNode updates the details of the transaction creator node
+func updateNode(db *LedgerDB, args []string) (Node, error) {
+	inp := inputNode{}
+	if err := AssetFromJSON(args, &inp); err != nil {
+		return Node{}, err
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return Node{}, err
+	}
+	node, err := db.GetNode(txCreator)
+	if err != nil {
+		return Node{}, err
+	}
+	node.setFromInput(inp)
+	return node, db.Put(node.ID, node)
+}
+
+// deregisterNode marks the transaction creator node as inactive. Existing
+// permissions and tuples are kept, but the node can no longer be granted new
+// permissions nor be assigned aggregate tuples.
+func deregisterNode(db *LedgerDB, args []string) (Node, error) {
+	if !isEmptyArgs(args) {
+		return Node{}, errors.BadRequest("incorrect number of arguments, expecting nothing")
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return Node{}, err
+	}
+	node, err := db.GetNode(txCreator)
+	if err != nil {
+		return Node{}, err
+	}
+	if node.Inactive {
+		return Node{}, errors.BadRequest("node %s is already deregistered", node.ID)
+	}
+	node.Inactive = true
+	return node, db.Put(node.ID, node)
+}
+
+func (node *Node) setFromInput(inp inputNode) {
+	node.Name = inp.Name
+	node.Organization = inp.Organization
+	node.Contact = inp.Contact
+	node.Metadata = inp.Metadata
+}
+
+// isEmptyArgs returns true if no argument, or a single empty one, is received
+func isEmptyArgs(args []string) bool {
+	return len(args) == 0 || (len(args) == 1 && args[0] == "")
+}
+
 func queryNodes(db *LedgerDB, args []string) (nodes []Node, err error) {
 	nodes = []Node{}
 
@@ -0,0 +1,170 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"net/http"
+	"strings"
+)
+
+// NodeGroupRefPrefix prefixes the key of a node group referenced in the
+// authorized IDs of a permission, e.g. "group:<key>"
+const NodeGroupRefPrefix = "group:"
+
+// registerNodeGroup stores a new node group owned by the transaction creator
+func registerNodeGroup(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputNodeGroup{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	owner, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if err = validateNodeGroupMembers(db, inp.Members); err != nil {
+		return
+	}
+	group := NodeGroup{
+		Key:       inp.Key,
+		Name:      inp.Name,
+		AssetType: NodeGroupType,
+		Owner:     owner,
+		Members:   inp.Members,
+	}
+	err = db.Add(group.Key, group)
+	if err != nil {
+		return
+	}
+	err = db.CreateIndex("nodeGroup~owner~key", []string{"nodeGroup", group.Owner, group.Key})
+	if err != nil {
+		return
+	}
+	return outputKey{Key: group.Key}, nil
+}
+
+// updateNodeGroup replaces the members of a node group. Only its owner can update it.
+// Permissions referencing the group follow its new membership.
+func updateNodeGroup(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputUpdateNodeGroup{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	group, err := db.GetNodeGroup(inp.Key)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != group.Owner {
+		return resp, errors.Forbidden("%s is not allowed to update node group %s", txCreator, inp.Key)
+	}
+	if err = validateNodeGroupMembers(db, inp.Members); err != nil {
+		return
+	}
+	group.Members = inp.Members
+	err = db.Put(group.Key, group)
+	if err != nil {
+		return
+	}
+	return outputKey{Key: group.Key}, nil
+}
+
+// queryNodeGroup returns a node group of the ledger given its key
+func queryNodeGroup(db *LedgerDB, args []string) (out outputNodeGroup, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	group, err := db.GetNodeGroup(inp.Key)
+	if err != nil {
+		return
+	}
+	out.Fill(group)
+	return
+}
+
+// queryNodeGroups returns all node groups of the ledger
+func queryNodeGroups(db *LedgerDB, args []string) (outGroups []outputNodeGroup, err error) {
+	outGroups = []outputNodeGroup{}
+	if len(args) != 0 {
+		err = errors.BadRequest("incorrect number of arguments, expecting nothing")
+		return
+	}
+	elementsKeys, err := db.GetIndexKeys("nodeGroup~owner~key", []string{"nodeGroup"})
+	if err != nil {
+		return
+	}
+	for _, key := range elementsKeys {
+		group, err := db.GetNodeGroup(key)
+		if err != nil {
+			return outGroups, err
+		}
+		var out outputNodeGroup
+		out.Fill(group)
+		outGroups = append(outGroups, out)
+	}
+	return
+}
+
+// validateNodeGroupMembers returns an error if one of the members is not a registered node.
+// Node groups can't be nested.
+func validateNodeGroupMembers(db *LedgerDB, members []string) error {
+	for _, member := range members {
+		if _, ok := parseNodeGroupRef(member); ok {
+			return errors.BadRequest("node group %s can't be a member of another node group", member)
+		}
+	}
+	return validateAuthorizedIds(db, members)
+}
+
+// parseNodeGroupRef returns the node group key of an authorized ID referencing a group
+func parseNodeGroupRef(authorizedID string) (string, bool) {
+	if !strings.HasPrefix(authorizedID, NodeGroupRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(authorizedID, NodeGroupRefPrefix), true
+}
+
+// getNodeGroupMembers returns the current members of a node group
+func getNodeGroupMembers(db *LedgerDB, groupKey string) ([]string, error) {
+	group, err := db.GetNodeGroup(groupKey)
+	if err != nil {
+		return nil, err
+	}
+	return group.Members, nil
+}
+
+func isNodeGroupMember(db *LedgerDB, groupKey, node string) (bool, error) {
+	members, err := getNodeGroupMembers(db, groupKey)
+	if err != nil {
+		return false, err
+	}
+	return stringInSlice(node, members), nil
+}
+
+// unknownNodeGroupError turns the error of a node group which doesn't exist
+// into a BadRequest, other errors are returned unchanged
+func unknownNodeGroupError(err error, groupKey string) error {
+	if errors.Wrap(err).HTTPStatusCode() == http.StatusNotFound {
+		return errors.BadRequest(err, "unknown node group %s", groupKey)
+	}
+	return err
+}
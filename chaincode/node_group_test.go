@@ -0,0 +1,108 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeGroupKey = "6b7c1de6-3f2a-4a8f-9c4d-0a1e2b3c4d5e"
+
+func TestNodeGroup(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputNodeGroup{Key: nodeGroupKey, Name: "research hospitals", Members: []string{workerB}}
+	_, err := registerNodeGroup(db, assetToArgs(inp))
+	require.NoError(t, err)
+
+	group, err := queryNodeGroup(db, keyToArgs(nodeGroupKey))
+	assert.NoError(t, err)
+	assert.Equal(t, outputNodeGroup{Key: nodeGroupKey, Name: inp.Name, Owner: workerA, Members: []string{workerB}}, group)
+
+	groups, err := queryNodeGroups(db, []string{})
+	assert.NoError(t, err)
+	assert.Len(t, groups, 1)
+
+	inp.Key = RandomUUID()
+	inp.Members = []string{"unknown"}
+	_, err = registerNodeGroup(db, assetToArgs(inp))
+	assert.Error(t, err, "members should be registered nodes")
+
+	inp.Members = []string{NodeGroupRefPrefix + nodeGroupKey}
+	_, err = registerNodeGroup(db, assetToArgs(inp))
+	assert.Error(t, err, "node groups can't be nested")
+
+	mockStub.Creator = workerB
+	_, err = updateNodeGroup(db, assetToArgs(inputUpdateNodeGroup{Key: nodeGroupKey}))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode(), "only the owner can update the group")
+}
+
+func TestNodeGroupPermissions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerWorker(mockStub, "SampleOrgC")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := registerNodeGroup(db, assetToArgs(inputNodeGroup{Key: nodeGroupKey, Name: "hospitals", Members: []string{workerB}}))
+	require.NoError(t, err)
+	groupRef := NodeGroupRefPrefix + nodeGroupKey
+
+	perms, err := NewPermissions(db, inputPermissions{Process: inputPermission{AuthorizedIDs: []string{groupRef}}})
+	require.NoError(t, err)
+	assert.True(t, canProcess(t, db, perms, workerA, workerB), "group members can process")
+	assert.False(t, canProcess(t, db, perms, workerA, "SampleOrgC"))
+
+	_, err = NewPermissions(db, inputPermissions{Process: inputPermission{AuthorizedIDs: []string{NodeGroupRefPrefix + RandomUUID()}}})
+	assert.Error(t, err, "referenced node groups should exist")
+
+	other := Permission{AuthorizedIDs: []string{workerB, "SampleOrgC"}}
+	merged, err := mergePermissions(db, perms.Process, other)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{workerB}, merged.AuthorizedIDs, "a group listed on one side only is resolved to its members")
+	merged, err = mergePermissions(db, perms.Process, Permission{AuthorizedIDs: []string{groupRef}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{groupRef}, merged.AuthorizedIDs, "a group listed on both sides is kept")
+	included, err := other.include(db, Permission{AuthorizedIDs: []string{groupRef}})
+	require.NoError(t, err)
+	assert.True(t, included)
+	included, err = Permission{AuthorizedIDs: []string{"SampleOrgC"}}.include(db, Permission{AuthorizedIDs: []string{groupRef}})
+	require.NoError(t, err)
+	assert.False(t, included)
+
+	// a reference to a node group which doesn't exist is an error rather than no member
+	unknown := Permissions{Process: Permission{AuthorizedIDs: []string{NodeGroupRefPrefix + RandomUUID()}}}
+	_, err = unknown.CanProcess(db, workerA, workerB)
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+	assert.Contains(t, err.Error(), "unknown node group")
+	_, err = mergePermissions(db, unknown.Process, other)
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+
+	_, err = updateNodeGroup(db, assetToArgs(inputUpdateNodeGroup{Key: nodeGroupKey, Members: []string{"SampleOrgC"}}))
+	require.NoError(t, err)
+	assert.False(t, canProcess(t, db, perms, workerA, workerB), "membership is resolved at check time")
+	assert.True(t, canProcess(t, db, perms, workerA, "SampleOrgC"))
+}
@@ -15,9 +15,11 @@
 package main
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNode(t *testing.T) {
@@ -44,3 +46,83 @@ func TestQueryNodes(t *testing.T) {
 	assert.EqualValuesf(t, 200, response.Status, "Node Created")
 	assert.Contains(t, string(response.Payload), "\"id\":\"SampleOrg\"", "Query nodes")
 }
+
+func TestNodeRegistry(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+
+	inp := inputNode{Name: "Hospital B", Organization: "Org B", Contact: "admin@b.org"}
+	mockStub.Creator = workerB
+	resp := mockStub.MockInvoke(append([][]byte{[]byte("registerNode")}, assetToJSON(inp)))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	node := Node{}
+	json.Unmarshal(resp.Payload, &node)
+	assert.Equal(t, workerB, node.ID)
+	assert.Equal(t, "Hospital B", node.Name)
+	assert.NotZero(t, node.RegisteredAt)
+	assert.False(t, node.Inactive)
+
+	inp.Contact = "contact@b.org"
+	resp = mockStub.MockInvoke(append([][]byte{[]byte("updateNode")}, assetToJSON(inp)))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	json.Unmarshal(resp.Payload, &node)
+	assert.Equal(t, "contact@b.org", node.Contact)
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("deregisterNode")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	json.Unmarshal(resp.Payload, &node)
+	assert.True(t, node.Inactive)
+
+	mockStub.Creator = workerA
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	err := validateAuthorizedIds(db, []string{workerB})
+	assert.Error(t, err, "an inactive node can't be granted permissions")
+
+	inpAgg := inputAggregatetuple{}
+	inpAgg.fillDefaults()
+	inpAgg.Worker = workerB
+	resp = mockStub.MockInvoke(inpAgg.getArgs())
+	assert.EqualValues(t, 400, resp.Status, "an inactive node can't be an aggregate worker")
+	assert.Contains(t, resp.Message, "deregistered")
+}
+
+func TestRegisterNodeAgain(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStub("substra", scc)
+	mockStub.Creator = workerB
+
+	inp := inputNode{Name: "Hospital B", Organization: "Org B"}
+	resp := mockStub.MockInvoke(append([][]byte{[]byte("registerNode")}, assetToJSON(inp)))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	registered := Node{}
+	json.Unmarshal(resp.Payload, &registered)
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	node := Node{}
+	json.Unmarshal(resp.Payload, &node)
+	assert.Equal(t, registered, node, "registering again without details should leave the node unchanged")
+
+	inp.Name = "Hospital B (east)"
+	resp = mockStub.MockInvoke(append([][]byte{[]byte("registerNode")}, assetToJSON(inp)))
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	json.Unmarshal(resp.Payload, &node)
+	assert.Equal(t, "Hospital B (east)", node.Name, "registering again should apply the details given")
+	assert.Equal(t, registered.RegisteredAt, node.RegisteredAt)
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("deregisterNode")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	resp = mockStub.MockInvoke([][]byte{[]byte("registerNode")})
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	json.Unmarshal(resp.Payload, &node)
+	assert.False(t, node.Inactive, "registering again should reactivate a deregistered node")
+	assert.Equal(t, "Hospital B (east)", node.Name)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	assert.NoError(t, validateAuthorizedIds(db, []string{workerB}), "a reactivated node can be granted permissions")
+}
@@ -16,6 +16,8 @@ package main
 
 import (
 	"chaincode/errors"
+	"fmt"
+	"reflect"
 	"sort"
 )
 
@@ -23,23 +25,9 @@ import (
 // Returns the objectiveKey and the dataManagerKey associated to test dataSample
 func (objective *Objective) Set(db *LedgerDB, inp inputObjective) (dataManagerKey string, err error) {
 	dataManagerKey = inp.TestDataset.DataManagerKey
-	if dataManagerKey != "" {
-		var testOnly bool
-		dataSampleKeys := inp.TestDataset.DataSampleKeys
-		testOnly, _, err = checkSameDataManager(db, dataManagerKey, dataSampleKeys)
-		if err != nil {
-			err = errors.BadRequest(err, "invalid test dataSample")
-			return
-		} else if !testOnly {
-			err = errors.BadRequest("test dataSample are not tagged as testOnly dataSample")
-			return
-		}
-		objective.TestDataset = &Dataset{
-			DataManagerKey: dataManagerKey,
-			DataSampleKeys: dataSampleKeys,
-		}
-	} else {
-		objective.TestDataset = nil
+	objective.TestDataset, err = getObjectiveTestDataset(db, inp.TestDataset)
+	if err != nil {
+		return
 	}
 	objective.Key = inp.Key
 	objective.AssetType = ObjectiveType
@@ -64,9 +52,22 @@ func (objective *Objective) Set(db *LedgerDB, inp inputObjective) (dataManagerKe
 	}
 	objective.Owner = owner
 	objective.Permissions = permissions
+	objective.Version = 1
 	return
 }
 
+// getVersion returns a snapshot of the current version of the objective
+func (objective *Objective) getVersion(createdAt int64) ObjectiveVersion {
+	return ObjectiveVersion{
+		ObjectiveKey: objective.Key,
+		Version:      objective.Version,
+		Description:  objective.Description,
+		Metrics:      objective.Metrics,
+		TestDataset:  objective.TestDataset,
+		CreatedAt:    createdAt,
+	}
+}
+
 // -------------------------------------------------------------------------------------------
 // Smart contract related to objectivess
 // -------------------------------------------------------------------------------------------
@@ -95,6 +96,9 @@ func registerObjective(db *LedgerDB, args []string) (resp outputKey, err error)
 	if err = db.CreateIndex("objective~owner~key", []string{"objective", objective.Owner, objective.Key}); err != nil {
 		return
 	}
+	if err = putObjectiveVersion(db, objective); err != nil {
+		return
+	}
 	// add objective to dataManager
 	err = addObjectiveDataManager(db, dataManagerKey, objective.Key)
 	return outputKey{Key: objective.Key}, err
@@ -151,6 +155,8 @@ func queryObjectives(db *LedgerDB, args []string) (outObjectives []outputObjecti
 
 // getObjectiveLeaderboard returns for an objective, all its certified testtuples with a done status, ordered by their perf
 // It can be an ascending sort or not depending on the ascendingOrder value.
+// Only the testtuples certified against the requested version of the objective are listed,
+// the current version being used by default.
 func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard, error) {
 	inp := inputLeaderboard{}
 	err := AssetFromJSON(args, &inp)
@@ -162,8 +168,17 @@ func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard,
 	if err != nil {
 		return outputLeaderboard{}, err
 	}
+	version := objective.Version
+	if inp.Version != 0 {
+		version = inp.Version
+	}
+	objectiveVersion, err := db.GetObjectiveVersion(inp.ObjectiveKey, version)
+	if err != nil {
+		return outputLeaderboard{}, err
+	}
 	outObjective := outputObjective{}
 	outObjective.Fill(objective)
+	outObjective.FillVersion(objectiveVersion)
 	out := outputLeaderboard{Objective: outObjective, Testtuples: []outputBoardTuple{}}
 
 	testtupleKeys, err := db.GetIndexKeys("testtuple~objective~certified~key", []string{"testtuple", inp.ObjectiveKey, "true"})
@@ -177,7 +192,8 @@ func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard,
 		if err != nil {
 			return outputLeaderboard{}, err
 		}
-		if testtuple.Status != StatusDone {
+		// results certified against another version are not comparable
+		if testtuple.Status != StatusDone || testtuple.ObjectiveVersion != version {
 			continue
 		}
 		err = boardTuple.Fill(db, testtuple, testtupleKey)
@@ -195,6 +211,142 @@ func queryObjectiveLeaderboard(db *LedgerDB, args []string) (outputLeaderboard,
 	return out, nil
 }
 
+// updateObjective changes the metrics, description or test dataset of an objective,
+// or removes its test dataset, and bumps its version. Previous versions stay queryable, and so do the leaderboards
+// of the testtuples certified against them. Only the objective owner can update it.
+func updateObjective(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputUpdateObjective{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	objective, err := db.GetObjective(inp.Key)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != objective.Owner {
+		return resp, errors.Forbidden("%s is not allowed to update objective %s", txCreator, inp.Key)
+	}
+
+	updated := objective
+	if inp.DescriptionStorageAddress != "" {
+		description := ChecksumAddress{}
+		if objective.Description != nil {
+			description = *objective.Description
+		}
+		if inp.DescriptionChecksum != "" {
+			description.Checksum = inp.DescriptionChecksum
+		}
+		description.StorageAddress = inp.DescriptionStorageAddress
+		updated.Description = &description
+	}
+	if inp.MetricsName != "" || inp.MetricsStorageAddress != "" {
+		metrics := ChecksumAddressName{}
+		if objective.Metrics != nil {
+			metrics = *objective.Metrics
+		}
+		if inp.MetricsName != "" {
+			metrics.Name = inp.MetricsName
+		}
+		if inp.MetricsChecksum != "" {
+			metrics.Checksum = inp.MetricsChecksum
+		}
+		if inp.MetricsStorageAddress != "" {
+			metrics.StorageAddress = inp.MetricsStorageAddress
+		}
+		updated.Metrics = &metrics
+	}
+	if inp.RemoveTestDataset {
+		if inp.TestDataset.DataManagerKey != "" {
+			return resp, errors.BadRequest("test_dataset and remove_test_dataset can't be set together")
+		}
+		updated.TestDataset = nil
+	} else if inp.TestDataset.DataManagerKey != "" {
+		updated.TestDataset, err = getObjectiveTestDataset(db, inp.TestDataset)
+		if err != nil {
+			return
+		}
+	}
+	if reflect.DeepEqual(objective.getVersion(0), updated.getVersion(0)) {
+		return resp, errors.BadRequest("objective %s is left unchanged", inp.Key)
+	}
+	// check the new test dataset before writing anything
+	var dataManager DataManager
+	if updated.TestDataset != nil {
+		dataManager, err = db.GetDataManager(updated.TestDataset.DataManagerKey)
+		if err != nil {
+			return
+		}
+		if dataManager.ObjectiveKey != "" && dataManager.ObjectiveKey != updated.Key {
+			return resp, errors.BadRequest("dataManager %s is already associated with objective %s", dataManager.Key, dataManager.ObjectiveKey)
+		}
+		for _, dataSampleKey := range updated.TestDataset.DataSampleKeys {
+			if objective.TestDataset != nil && stringInSlice(dataSampleKey, objective.TestDataset.DataSampleKeys) {
+				continue
+			}
+			if err = checkDataSampleNotInUse(db, dataSampleKey); err != nil {
+				return
+			}
+		}
+	}
+
+	// objectives registered before versioning have no snapshot of their current version yet
+	ok, err := db.KeyExists(getObjectiveVersionKey(objective.Key, objective.Version))
+	if err != nil {
+		return
+	}
+	if !ok {
+		if err = putObjectiveVersion(db, objective); err != nil {
+			return
+		}
+	}
+
+	updated.Version++
+	if err = db.Put(updated.Key, updated); err != nil {
+		return
+	}
+	if err = putObjectiveVersion(db, updated); err != nil {
+		return
+	}
+	// the data manager of a previous test dataset stays associated with the objective
+	// since the previous versions still refer to it
+	if updated.TestDataset != nil && dataManager.ObjectiveKey != updated.Key {
+		if err = addObjectiveDataManager(db, dataManager.Key, updated.Key); err != nil {
+			return
+		}
+	}
+	return outputKey{Key: updated.Key}, nil
+}
+
+// queryObjectiveVersions returns all the versions of an objective, the first one first
+func queryObjectiveVersions(db *LedgerDB, args []string) (outObjectives []outputObjective, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	objective, err := db.GetObjective(inp.Key)
+	if err != nil {
+		return
+	}
+	outObjectives = []outputObjective{}
+	for version := 1; version <= objective.Version; version++ {
+		objectiveVersion, err := db.GetObjectiveVersion(objective.Key, version)
+		if err != nil {
+			return nil, err
+		}
+		var out outputObjective
+		out.Fill(objective)
+		out.FillVersion(objectiveVersion)
+		outObjectives = append(outObjectives, out)
+	}
+	return
+}
+
 // -------------------------------------------------------------------------------------------
 // Utils for objectivess
 // -------------------------------------------------------------------------------------------
@@ -211,3 +363,34 @@ func addObjectiveDataManager(db *LedgerDB, dataManagerKey string, objectiveKey s
 	dataManager.ObjectiveKey = objectiveKey
 	return db.Put(dataManagerKey, dataManager)
 }
+
+// getObjectiveTestDataset checks that the test dataset of an objective only contains
+// test only data samples of the given data manager
+func getObjectiveTestDataset(db *LedgerDB, inp inputDataset) (*Dataset, error) {
+	if inp.DataManagerKey == "" {
+		return nil, nil
+	}
+	testOnly, _, err := checkSameDataManager(db, inp.DataManagerKey, inp.DataSampleKeys)
+	if err != nil {
+		return nil, errors.BadRequest(err, "invalid test dataSample")
+	} else if !testOnly {
+		return nil, errors.BadRequest("test dataSample are not tagged as testOnly dataSample")
+	}
+	return &Dataset{
+		DataManagerKey: inp.DataManagerKey,
+		DataSampleKeys: inp.DataSampleKeys,
+	}, nil
+}
+
+// putObjectiveVersion records a snapshot of the current version of an objective
+func putObjectiveVersion(db *LedgerDB, objective Objective) error {
+	txTime, err := GetTxTime(db.cc)
+	if err != nil {
+		return err
+	}
+	return db.Add(getObjectiveVersionKey(objective.Key, objective.Version), objective.getVersion(txTime.Unix()))
+}
+
+func getObjectiveVersionKey(objectiveKey string, version int) string {
+	return fmt.Sprintf("objective~%v~version~%v", objectiveKey, version)
+}
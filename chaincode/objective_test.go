@@ -15,7 +15,9 @@
 package main
 
 import (
+	"chaincode/errors"
 	"encoding/json"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -71,6 +73,223 @@ func TestLeaderBoard(t *testing.T) {
 	assert.Equal(t, algoName, leaderboard.Testtuples[0].Algo.Name)
 	assert.Equal(t, algoStorageAddress, leaderboard.Testtuples[0].Algo.StorageAddress)
 }
+
+func TestLeaderBoardObjectiveVersions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	db := NewLedgerDB(mockStub)
+	registerItem(t, *mockStub, "")
+	mockStub.MockTransactionStart("42")
+
+	inputTest := inputTesttuple{
+		TraintupleKey: traintupleKey,
+		ObjectiveKey:  objectiveKey,
+	}
+	inputTest.fillDefaults()
+	keyMap, err := createTesttuple(db, assetToArgs(inputTest))
+	require.NoError(t, err)
+	testtuple, err := db.GetTesttuple(keyMap.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, testtuple.ObjectiveVersion)
+	testtuple.Status = StatusDone
+	require.NoError(t, db.Put(keyMap.Key, testtuple))
+
+	// record a second version of the objective with new metrics
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{
+		Key:                   objectiveKey,
+		MetricsName:           "new metrics",
+		MetricsChecksum:       GetRandomHash(),
+		MetricsStorageAddress: "https://toto/metrics/v2",
+	}))
+	require.NoError(t, err)
+	objective, err := db.GetObjective(objectiveKey)
+	require.NoError(t, err)
+	require.Equal(t, 2, objective.Version)
+
+	// the leaderboard defaults to the current version
+	inpLeaderboard := inputLeaderboard{ObjectiveKey: objectiveKey, AscendingOrder: true}
+	leaderboard, err := queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	require.NoError(t, err)
+	assert.Equal(t, 2, leaderboard.Objective.Version)
+	assert.Equal(t, objective.Metrics, leaderboard.Objective.Metrics)
+	assert.Len(t, leaderboard.Testtuples, 0)
+
+	inpLeaderboard.Version = 1
+	leaderboard, err = queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	require.NoError(t, err)
+	assert.Equal(t, 1, leaderboard.Objective.Version)
+	assert.Equal(t, objectiveMetricsChecksum, leaderboard.Objective.Metrics.Checksum)
+	require.Len(t, leaderboard.Testtuples, 1)
+	assert.Equal(t, keyMap.Key, leaderboard.Testtuples[0].Key)
+
+	inpLeaderboard.Version = 3
+	_, err = queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	assert.Equal(t, http.StatusNotFound, errors.Wrap(err).HTTPStatusCode())
+
+	// the testtuple keeps the metrics of the version it was created against
+	out, err := queryTesttuple(db, keyToArgs(keyMap.Key))
+	require.NoError(t, err)
+	assert.Equal(t, 1, out.Objective.Version)
+	assert.Equal(t, objectiveMetricsChecksum, out.Objective.Metrics.Checksum)
+}
+
+func TestUpdateObjective(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	db := NewLedgerDB(mockStub)
+	registerItem(t, *mockStub, "")
+	mockStub.MockTransactionStart("42")
+
+	inputTest := inputTesttuple{
+		TraintupleKey: traintupleKey,
+		ObjectiveKey:  objectiveKey,
+	}
+	inputTest.fillDefaults()
+	keyMap, err := createTesttuple(db, assetToArgs(inputTest))
+	require.NoError(t, err)
+	testtuple, err := db.GetTesttuple(keyMap.Key)
+	require.NoError(t, err)
+	testtuple.Status = StatusDone
+	require.NoError(t, db.Put(keyMap.Key, testtuple))
+
+	update := inputUpdateObjective{
+		Key:                   objectiveKey,
+		MetricsChecksum:       GetRandomHash(),
+		MetricsStorageAddress: "https://toto/metrics/v2",
+		TestDataset: inputDataset{
+			DataManagerKey: dataManagerKey,
+			DataSampleKeys: []string{testDataSampleKey1},
+		},
+	}
+	mockStub.Creator = workerB
+	_, err = updateObjective(db, assetToArgs(update))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+	mockStub.Creator = workerA
+
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{Key: objectiveKey, MetricsChecksum: GetRandomHash()}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "metrics checksum and address go together")
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{Key: objectiveKey, MetricsName: "accuracy"}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "nothing to update")
+
+	_, err = updateObjective(db, assetToArgs(update))
+	require.NoError(t, err)
+	objective, err := queryObjective(db, keyToArgs(objectiveKey))
+	require.NoError(t, err)
+	assert.Equal(t, 2, objective.Version)
+	assert.Equal(t, update.MetricsChecksum, objective.Metrics.Checksum)
+	assert.Equal(t, "accuracy", objective.Metrics.Name)
+
+	// previous versions stay queryable
+	versions, err := queryObjectiveVersions(db, keyToArgs(objectiveKey))
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+	assert.Equal(t, 1, versions[0].Version)
+	assert.Equal(t, objectiveMetricsChecksum, versions[0].Metrics.Checksum)
+	assert.Equal(t, []string{testDataSampleKey1, testDataSampleKey2}, versions[0].TestDataset.DataSampleKeys)
+	assert.Equal(t, 2, versions[1].Version)
+	assert.Equal(t, []string{testDataSampleKey1}, versions[1].TestDataset.DataSampleKeys)
+
+	// new certified testtuples use the new test dataset
+	inputTest.Key = RandomUUID()
+	keyMap, err = createTesttuple(db, assetToArgs(inputTest))
+	require.NoError(t, err)
+	testtuple, err = db.GetTesttuple(keyMap.Key)
+	require.NoError(t, err)
+	assert.True(t, testtuple.Certified)
+	assert.Equal(t, 2, testtuple.ObjectiveVersion)
+	assert.Equal(t, []string{testDataSampleKey1}, testtuple.Dataset.DataSampleKeys)
+
+	// and the leaderboard of the first version is kept
+	inpLeaderboard := inputLeaderboard{ObjectiveKey: objectiveKey, AscendingOrder: true}
+	leaderboard, err := queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	require.NoError(t, err)
+	assert.Len(t, leaderboard.Testtuples, 0)
+	inpLeaderboard.Version = 1
+	leaderboard, err = queryObjectiveLeaderboard(db, assetToArgs(inpLeaderboard))
+	require.NoError(t, err)
+	assert.Len(t, leaderboard.Testtuples, 1)
+
+	// the metrics script can be moved without changing its checksum
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{Key: objectiveKey, MetricsStorageAddress: "https://toto/metrics/v3"}))
+	require.NoError(t, err)
+	objective, err = queryObjective(db, keyToArgs(objectiveKey))
+	require.NoError(t, err)
+	assert.Equal(t, 3, objective.Version)
+	assert.Equal(t, update.MetricsChecksum, objective.Metrics.Checksum)
+	assert.Equal(t, "https://toto/metrics/v3", objective.Metrics.StorageAddress)
+	assert.Equal(t, "accuracy", objective.Metrics.Name)
+	assert.Equal(t, []string{testDataSampleKey1}, objective.TestDataset.DataSampleKeys)
+
+	// and the test dataset can be removed
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{Key: objectiveKey, RemoveTestDataset: true, TestDataset: update.TestDataset}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{Key: objectiveKey, RemoveTestDataset: true}))
+	require.NoError(t, err)
+	objective, err = queryObjective(db, keyToArgs(objectiveKey))
+	require.NoError(t, err)
+	assert.Equal(t, 4, objective.Version)
+	assert.Nil(t, objective.TestDataset)
+	assert.Equal(t, "https://toto/metrics/v3", objective.Metrics.StorageAddress)
+}
+
+func TestUpdateObjectiveInvalidTestDataset(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+
+	// a second objective with its own data manager
+	otherDataManager := inputDataManager{Key: RandomUUID()}
+	resp := mockStub.MockInvoke(otherDataManager.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	otherDataSample := inputDataSample{Keys: []string{RandomUUID()}, DataManagerKeys: []string{otherDataManager.Key}, TestOnly: "true"}
+	resp = mockStub.MockInvoke(otherDataSample.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+	otherObjective := inputObjective{
+		Key:         RandomUUID(),
+		TestDataset: inputDataset{DataManagerKey: otherDataManager.Key, DataSampleKeys: otherDataSample.Keys},
+	}
+	resp = mockStub.MockInvoke(otherObjective.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// the data manager of another objective is rejected before anything is written
+	_, err := updateObjective(db, assetToArgs(inputUpdateObjective{
+		Key:         objectiveKey,
+		TestDataset: inputDataset{DataManagerKey: otherDataManager.Key, DataSampleKeys: otherDataSample.Keys},
+	}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+	assert.Contains(t, err.Error(), otherObjective.Key)
+	objective, err := db.GetObjective(objectiveKey)
+	require.NoError(t, err)
+	assert.Equal(t, 1, objective.Version)
+	ok, err := db.KeyExists(getObjectiveVersionKey(objectiveKey, 2))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// so is a new test data sample still used by a pending tuple,
+	// e.g. a traintuple registered before it was made test only
+	dataSampleKey := RandomUUID()
+	inpDataSample := inputDataSample{Keys: []string{dataSampleKey}, DataManagerKeys: []string{dataManagerKey}, TestOnly: "true"}
+	_, err = registerDataSample(db, assetToArgs(inpDataSample))
+	require.NoError(t, err)
+	require.NoError(t, createDataSampleUsageIndex(db, []string{dataSampleKey}, traintupleKey))
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{
+		Key:         objectiveKey,
+		TestDataset: inputDataset{DataManagerKey: dataManagerKey, DataSampleKeys: []string{testDataSampleKey1, dataSampleKey}},
+	}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+	assert.Contains(t, err.Error(), traintupleKey)
+
+	// the data samples already in the test dataset are not checked again
+	_, err = updateObjective(db, assetToArgs(inputUpdateObjective{
+		Key:         objectiveKey,
+		TestDataset: inputDataset{DataManagerKey: dataManagerKey, DataSampleKeys: []string{testDataSampleKey1}},
+	}))
+	assert.NoError(t, err)
+}
+
 func TestRegisterObjectiveWhitoutDataset(t *testing.T) {
 	scc := new(SubstraChaincode)
 	mockStub := NewMockStubWithRegisterNode("substra", scc)
@@ -169,8 +388,11 @@ func TestObjective(t *testing.T) {
 			StorageAddress: inpObjective.DescriptionStorageAddress,
 			Checksum:       objectiveDescriptionChecksum,
 		},
-		Permissions: outputPermissions{
-			Process: Permission{Public: true, AuthorizedIDs: []string{}},
+		Permissions: outputPermissionsFull{
+			outputPermissions: outputPermissions{
+				Process: Permission{Public: true, AuthorizedIDs: []string{}},
+			},
+			Download: Permission{Public: true, AuthorizedIDs: []string{}},
 		},
 		Metrics: &ChecksumAddressName{
 			Checksum:       inpObjective.MetricsChecksum,
@@ -178,6 +400,7 @@ func TestObjective(t *testing.T) {
 			StorageAddress: inpObjective.MetricsStorageAddress,
 		},
 		Metadata: map[string]string{},
+		Version:  1,
 	}
 	assert.Exactly(t, expectedObjective, objective)
 
@@ -25,14 +25,15 @@ const OutputPageSize = 500
 // Struct use as output representation of ledger data
 
 type outputObjective struct {
-	Key         string               `json:"key"`
-	Name        string               `json:"name"`
-	Description *ChecksumAddress     `json:"description"`
-	Metrics     *ChecksumAddressName `json:"metrics"`
-	Owner       string               `json:"owner"`
-	TestDataset *Dataset             `json:"test_dataset"`
-	Permissions outputPermissions    `json:"permissions"`
-	Metadata    map[string]string    `json:"metadata"`
+	Key         string                `json:"key"`
+	Name        string                `json:"name"`
+	Description *ChecksumAddress      `json:"description"`
+	Metrics     *ChecksumAddressName  `json:"metrics"`
+	Owner       string                `json:"owner"`
+	TestDataset *Dataset              `json:"test_dataset"`
+	Permissions outputPermissionsFull `json:"permissions"`
+	Metadata    map[string]string     `json:"metadata"`
+	Version     int                   `json:"version"`
 }
 
 func (out *outputObjective) Fill(in Objective) {
@@ -47,19 +48,31 @@ func (out *outputObjective) Fill(in Objective) {
 	}
 	out.Permissions.Fill(in.Permissions)
 	out.Metadata = initMapOutput(in.Metadata)
+	out.Version = in.Version
+}
+
+// FillVersion replaces the versioned fields of the objective by the ones of `in`
+func (out *outputObjective) FillVersion(in ObjectiveVersion) {
+	out.Description = in.Description
+	out.Metrics = in.Metrics
+	out.TestDataset = in.TestDataset
+	if out.TestDataset != nil {
+		out.TestDataset.Metadata = initMapOutput(in.TestDataset.Metadata)
+	}
+	out.Version = in.Version
 }
 
 // outputDataManager is the return representation of the DataManager type stored in the ledger
 type outputDataManager struct {
-	ObjectiveKey string            `json:"objective_key"`
-	Description  *ChecksumAddress  `json:"description"`
-	Key          string            `json:"key"`
-	Metadata     map[string]string `json:"metadata"`
-	Name         string            `json:"name"`
-	Opener       *ChecksumAddress  `json:"opener"`
-	Owner        string            `json:"owner"`
-	Permissions  outputPermissions `json:"permissions"`
-	Type         string            `json:"type"`
+	ObjectiveKey string                `json:"objective_key"`
+	Description  *ChecksumAddress      `json:"description"`
+	Key          string                `json:"key"`
+	Metadata     map[string]string     `json:"metadata"`
+	Name         string                `json:"name"`
+	Opener       *ChecksumAddress      `json:"opener"`
+	Owner        string                `json:"owner"`
+	Permissions  outputPermissionsFull `json:"permissions"`
+	Type         string                `json:"type"`
 }
 
 func (out *outputDataManager) Fill(in DataManager) {
@@ -74,16 +87,36 @@ func (out *outputDataManager) Fill(in DataManager) {
 	out.Type = in.Type
 }
 
+// restrictDownload hides the opener storage address from a node which is
+// not allowed to download the data manager
+func (out *outputDataManager) restrictDownload(db *LedgerDB, in DataManager, node string) error {
+	if in.Opener == nil {
+		return nil
+	}
+	allowed, err := in.Permissions.CanDownload(db, in.Owner, node)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		out.Opener = &ChecksumAddress{Checksum: in.Opener.Checksum}
+	}
+	return nil
+}
+
 type outputDataSample struct {
-	DataManagerKeys []string `json:"data_manager_keys"`
-	Owner           string   `json:"owner"`
-	Key             string   `json:"key"`
+	DataManagerKeys []string          `json:"data_manager_keys"`
+	Owner           string            `json:"owner"`
+	Key             string            `json:"key"`
+	Metadata        map[string]string `json:"metadata"`
+	PendingOwner    string            `json:"pending_owner"`
 }
 
 func (out *outputDataSample) Fill(key string, in DataSample) {
 	out.Key = key
 	out.DataManagerKeys = in.DataManagerKeys
 	out.Owner = in.Owner
+	out.Metadata = initMapOutput(in.Metadata)
+	out.PendingOwner = in.PendingOwner
 }
 
 type outputDataset struct {
@@ -101,13 +134,13 @@ func (out *outputDataset) Fill(in DataManager, trainKeys []string, testKeys []st
 }
 
 type outputAlgo struct {
-	Key         string            `json:"key"`
-	Name        string            `json:"name"`
-	Content     *ChecksumAddress  `json:"content"`
-	Description *ChecksumAddress  `json:"description"`
-	Owner       string            `json:"owner"`
-	Permissions outputPermissions `json:"permissions"`
-	Metadata    map[string]string `json:"metadata"`
+	Key         string                `json:"key"`
+	Name        string                `json:"name"`
+	Content     *ChecksumAddress      `json:"content"`
+	Description *ChecksumAddress      `json:"description"`
+	Owner       string                `json:"owner"`
+	Permissions outputPermissionsFull `json:"permissions"`
+	Metadata    map[string]string     `json:"metadata"`
 }
 
 func (out *outputAlgo) Fill(in Algo) {
@@ -123,6 +156,46 @@ func (out *outputAlgo) Fill(in Algo) {
 	out.Metadata = initMapOutput(in.Metadata)
 }
 
+// restrictDownload hides the algo storage address from a node which is not
+// allowed to download the algo
+func (out *outputAlgo) restrictDownload(db *LedgerDB, in Algo, node string) error {
+	allowed, err := in.Permissions.CanDownload(db, in.Owner, node)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		out.Content.StorageAddress = ""
+	}
+	return nil
+}
+
+// restrictAddress returns the storage address of an asset used or produced by
+// a tuple, or an empty string if the node is neither the tuple worker, which
+// has to fetch the assets it processes, nor allowed to download the asset
+func restrictAddress(db *LedgerDB, perms Permissions, owner, worker, node, storageAddress string) (string, error) {
+	if node == worker {
+		return storageAddress, nil
+	}
+	allowed, err := perms.CanDownload(db, owner, node)
+	if err != nil || !allowed {
+		return "", err
+	}
+	return storageAddress, nil
+}
+
+// restrictInModelAddress is restrictAddress for an in-model, given the key of
+// the tuple or external model it comes from
+func restrictInModelAddress(db *LedgerDB, inModelKey string, head bool, worker, node, storageAddress string) (string, error) {
+	if node == worker || storageAddress == "" {
+		return storageAddress, nil
+	}
+	perms, owner, _, err := getOutModelPermissions(db, inModelKey, head)
+	if err != nil {
+		return "", err
+	}
+	return restrictAddress(db, perms, owner, worker, node, storageAddress)
+}
+
 // outputTtDataset is the representation of a Traintuple Dataset
 type outputTtDataset struct {
 	Key            string            `json:"key"`
@@ -142,27 +215,53 @@ type outputTraintuple struct {
 	ComputePlanKey string                  `json:"compute_plan_key"`
 	InModels       []*Model                `json:"in_models"`
 	Log            string                  `json:"log"`
+	LogHash        string                  `json:"log_hash,omitempty"`
 	Metadata       map[string]string       `json:"metadata"`
 	OutModel       *KeyChecksumAddress     `json:"out_model"`
 	Permissions    outputPermissions       `json:"permissions"`
+	Progress       *TupleProgress          `json:"progress"`
 	Rank           int                     `json:"rank"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
+	KeepModel      bool                    `json:"keep_model"`
 }
 
 //Fill is a method of the receiver outputTraintuple. It returns all elements necessary to do a training task from a trainuple stored in the ledger
-func (outputTraintuple *outputTraintuple) Fill(db *LedgerDB, traintuple Traintuple) (err error) {
+func (outputTraintuple *outputTraintuple) Fill(db *LedgerDB, traintuple Traintuple) error {
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	return outputTraintuple.fillAs(db, traintuple, node)
+}
+
+// fillAs fills the traintuple as seen by the given node: storage addresses of
+// the assets the node is not allowed to download are left empty
+func (outputTraintuple *outputTraintuple) fillAs(db *LedgerDB, traintuple Traintuple, node string) (err error) {
 
 	outputTraintuple.Key = traintuple.Key
 	outputTraintuple.Creator = traintuple.Creator
 	outputTraintuple.Permissions.Fill(traintuple.Permissions)
 	outputTraintuple.Log = traintuple.Log
+	outputTraintuple.LogHash = traintuple.LogHash
 	outputTraintuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputTraintuple.Status = traintuple.Status
 	outputTraintuple.Rank = traintuple.Rank
 	outputTraintuple.ComputePlanKey = traintuple.ComputePlanKey
-	outputTraintuple.OutModel = traintuple.OutModel
+	if traintuple.OutModel != nil {
+		outModel := *traintuple.OutModel
+		outModel.StorageAddress, err = restrictAddress(db, traintuple.Permissions, traintuple.Dataset.Worker, traintuple.Dataset.Worker, node, outModel.StorageAddress)
+		if err != nil {
+			return
+		}
+		outputTraintuple.OutModel = &outModel
+	}
 	outputTraintuple.Tag = traintuple.Tag
+	outputTraintuple.KeepModel = traintuple.KeepModel
+	outputTraintuple.Progress, err = db.GetTupleProgress(traintuple.Key)
+	if err != nil {
+		return
+	}
 	// fill algo
 	algo, err := db.GetAlgo(traintuple.AlgoKey)
 	if err != nil {
@@ -170,27 +269,43 @@ func (outputTraintuple *outputTraintuple) Fill(db *LedgerDB, traintuple Traintup
 		return
 	}
 	outputTraintuple.Algo = &KeyChecksumAddressName{
-		Key:            algo.Key,
-		Name:           algo.Name,
-		Checksum:       algo.Checksum,
-		StorageAddress: algo.StorageAddress}
+		Key:      algo.Key,
+		Name:     algo.Name,
+		Checksum: algo.Checksum}
+	outputTraintuple.Algo.StorageAddress, err = restrictAddress(db, algo.Permissions, algo.Owner, traintuple.Dataset.Worker, node, algo.StorageAddress)
+	if err != nil {
+		return
+	}
 
 	// fill inModels
 	for _, inModelKey := range traintuple.InModelKeys {
 		if inModelKey == "" {
 			break
 		}
+		inModel := &Model{
+			TraintupleKey: inModelKey,
+		}
+		if externalModel, err := db.GetExternalModel(inModelKey); err == nil {
+			inModel.Key = externalModel.Key
+			inModel.Checksum = externalModel.Checksum
+			inModel.StorageAddress, err = restrictAddress(db, externalModel.Permissions, externalModel.Owner, traintuple.Dataset.Worker, node, externalModel.StorageAddress)
+			if err != nil {
+				return err
+			}
+			outputTraintuple.InModels = append(outputTraintuple.InModels, inModel)
+			continue
+		}
 		parentTraintuple, err := db.GetTraintuple(inModelKey)
 		if err != nil {
 			return errors.Internal("could not retrieve parent traintuple with key %s - %s", inModelKey, err.Error())
 		}
-		inModel := &Model{
-			TraintupleKey: inModelKey,
-		}
 		if parentTraintuple.OutModel != nil {
 			inModel.Key = parentTraintuple.Key
 			inModel.Checksum = parentTraintuple.OutModel.Checksum
-			inModel.StorageAddress = parentTraintuple.OutModel.StorageAddress
+			inModel.StorageAddress, err = restrictAddress(db, parentTraintuple.Permissions, parentTraintuple.Dataset.Worker, traintuple.Dataset.Worker, node, parentTraintuple.OutModel.StorageAddress)
+			if err != nil {
+				return err
+			}
 		}
 		outputTraintuple.InModels = append(outputTraintuple.InModels, inModel)
 	}
@@ -221,8 +336,10 @@ type outputTesttuple struct {
 	Dataset        *TtDataset              `json:"dataset"`
 	Key            string                  `json:"key"`
 	Log            string                  `json:"log"`
+	LogHash        string                  `json:"log_hash,omitempty"`
 	Metadata       map[string]string       `json:"metadata"`
 	Objective      *TtObjective            `json:"objective"`
+	Progress       *TupleProgress          `json:"progress"`
 	Rank           int                     `json:"rank"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
@@ -231,17 +348,33 @@ type outputTesttuple struct {
 }
 
 func (out *outputTesttuple) Fill(db *LedgerDB, in Testtuple) error {
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	return out.fillAs(db, in, node)
+}
+
+// fillAs fills the testtuple as seen by the given node: the algo storage
+// address is left empty if the node is not allowed to download the algo
+func (out *outputTesttuple) fillAs(db *LedgerDB, in Testtuple, node string) error {
 	out.Key = in.Key
 	out.Certified = in.Certified
 	out.ComputePlanKey = in.ComputePlanKey
 	out.Creator = in.Creator
 	out.Dataset = in.Dataset
 	out.Log = in.Log
+	out.LogHash = in.LogHash
 	out.Metadata = initMapOutput(in.Metadata)
 	out.Rank = in.Rank
 	out.Status = in.Status
 	out.Tag = in.Tag
 	out.TraintupleKey = in.TraintupleKey
+	progress, err := db.GetTupleProgress(in.Key)
+	if err != nil {
+		return err
+	}
+	out.Progress = progress
 
 	// fill type
 	traintupleType, err := db.GetAssetType(in.TraintupleKey)
@@ -272,13 +405,16 @@ func (out *outputTesttuple) Fill(db *LedgerDB, in Testtuple) error {
 		algo = aggregateAlgo.Algo
 	}
 	out.Algo = &KeyChecksumAddressName{
-		Key:            algo.Key,
-		Name:           algo.Name,
-		Checksum:       algo.Checksum,
-		StorageAddress: algo.StorageAddress}
+		Key:      algo.Key,
+		Name:     algo.Name,
+		Checksum: algo.Checksum}
+	out.Algo.StorageAddress, err = restrictAddress(db, algo.Permissions, algo.Owner, in.Dataset.Worker, node, algo.StorageAddress)
+	if err != nil {
+		return err
+	}
 
-	// fill objective
-	objective, err := db.GetObjective(in.ObjectiveKey)
+	// fill objective with the version the testtuple was created against
+	objective, err := db.GetObjectiveVersion(in.ObjectiveKey, in.ObjectiveVersion)
 	if err != nil {
 		return errors.Internal("could not retrieve associated objective with key %s- %s", in.ObjectiveKey, err.Error())
 	}
@@ -292,6 +428,7 @@ func (out *outputTesttuple) Fill(db *LedgerDB, in Testtuple) error {
 	out.Objective = &TtObjective{
 		Key:     in.ObjectiveKey,
 		Metrics: &metrics,
+		Version: objective.Version,
 	}
 	return nil
 }
@@ -302,6 +439,7 @@ type outputModelDetails struct {
 	Traintuple             *outputTraintuple          `json:"traintuple,omitempty"`
 	Testtuple              outputTesttuple            `json:"testtuple"`
 	NonCertifiedTesttuples []outputTesttuple          `json:"non_certified_testtuples"`
+	DeletedModels          []ModelDeletion            `json:"deleted_models"`
 }
 
 type outputModelListItem struct {
@@ -310,11 +448,20 @@ type outputModelListItem struct {
 	Traintuple          *outputTraintuple          `json:"traintuple,omitempty"`
 }
 
+type outputWorkerTask struct {
+	Aggregatetuple      *outputAggregatetuple      `json:"aggregatetuple,omitempty"`
+	CompositeTraintuple *outputCompositeTraintuple `json:"composite_traintuple,omitempty"`
+	Testtuple           *outputTesttuple           `json:"testtuple,omitempty"`
+	Traintuple          *outputTraintuple          `json:"traintuple,omitempty"`
+}
+
 type outputModel struct {
 	Key            string                `json:"key"`
 	StorageAddress string                `json:"storage_address"`
 	Permissions    outputPermissionsFull `json:"permissions"`
 	Owner          string                `json:"owner"`
+	Deleted        bool                  `json:"deleted"`
+	DeletedAt      int64                 `json:"deleted_at,omitempty"`
 }
 
 // Event is the collection of tuples sent in an event
@@ -350,12 +497,9 @@ type outputComputePlan struct {
 
 func (out *outputComputePlan) Fill(key string, in ComputePlan, newIDs []string, doneCount int, tupleCount int) {
 	out.Key = key
-	nb := getLimitedNbSliceElements(in.TraintupleKeys)
-	out.TraintupleKeys = in.TraintupleKeys[:nb]
-	nb = getLimitedNbSliceElements(in.AggregatetupleKeys)
-	out.AggregatetupleKeys = in.AggregatetupleKeys[:nb]
-	nb = getLimitedNbSliceElements(in.CompositeTraintupleKeys)
-	out.CompositeTraintupleKeys = in.CompositeTraintupleKeys[:nb]
+	out.TraintupleKeys = in.TraintupleKeys
+	out.AggregatetupleKeys = in.AggregatetupleKeys
+	out.CompositeTraintupleKeys = in.CompositeTraintupleKeys
 	out.TesttupleKeys = in.TesttupleKeys
 	out.Status = in.State.Status
 	out.Tag = in.Tag
@@ -370,6 +514,78 @@ func (out *outputComputePlan) Fill(key string, in ComputePlan, newIDs []string,
 	out.CleanModels = in.CleanModels
 }
 
+type outputComputePlanTuple struct {
+	Key    string `json:"key"`
+	Type   string `json:"type"`
+	Status string `json:"status"`
+	Rank   int    `json:"rank"`
+}
+
+type outputComputePlanProgress struct {
+	Key     string                            `json:"key"`
+	Status  string                            `json:"status"`
+	Workers []outputComputePlanWorkerProgress `json:"workers"`
+}
+
+// outputComputePlanWorkerProgress details the progress of a compute plan for
+// a single worker. HighestDoneRank is -1 when no ranked tuple is done yet.
+type outputComputePlanWorkerProgress struct {
+	Worker               string            `json:"worker"`
+	TupleCount           int               `json:"tuple_count"`
+	DoneCount            int               `json:"done_count"`
+	HighestDoneRank      int               `json:"highest_done_rank"`
+	Traintuples          outputStatusCount `json:"traintuples"`
+	CompositeTraintuples outputStatusCount `json:"composite_traintuples"`
+	Aggregatetuples      outputStatusCount `json:"aggregatetuples"`
+	Testtuples           outputStatusCount `json:"testtuples"`
+}
+
+type outputStatusCount struct {
+	Waiting  int `json:"waiting"`
+	Todo     int `json:"todo"`
+	Doing    int `json:"doing"`
+	Done     int `json:"done"`
+	Failed   int `json:"failed"`
+	Canceled int `json:"canceled"`
+}
+
+// Add increments the counter matching the given status
+func (out *outputStatusCount) Add(status string) {
+	// the waiting tuples of a failed or canceled compute plan are aborted,
+	// they are counted with the canceled ones
+	if status == StatusAborted {
+		status = StatusCanceled
+	}
+	switch status {
+	case StatusWaiting:
+		out.Waiting++
+	case StatusTodo:
+		out.Todo++
+	case StatusDoing:
+		out.Doing++
+	case StatusDone:
+		out.Done++
+	case StatusFailed:
+		out.Failed++
+	case StatusCanceled:
+		out.Canceled++
+	}
+}
+
+// Add counts a tuple of the given type and status in the worker progress
+func (out *outputComputePlanWorkerProgress) Add(tupleType AssetType, status string) {
+	switch tupleType {
+	case TraintupleType:
+		out.Traintuples.Add(status)
+	case CompositeTraintupleType:
+		out.CompositeTraintuples.Add(status)
+	case AggregatetupleType:
+		out.Aggregatetuples.Add(status)
+	case TesttupleType:
+		out.Testtuples.Add(status)
+	}
+}
+
 // This is the "historical" output permissions, not
 // implementing "Download" permissions.
 type outputPermissions struct {
@@ -433,11 +649,18 @@ func (out *outputBoardTuple) Fill(db *LedgerDB, in Testtuple, testtupleKey strin
 	if err != nil {
 		return err
 	}
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
 	out.Algo = &KeyChecksumAddressName{
-		Key:            algo.Key,
-		Name:           algo.Name,
-		Checksum:       algo.Checksum,
-		StorageAddress: algo.StorageAddress,
+		Key:      algo.Key,
+		Name:     algo.Name,
+		Checksum: algo.Checksum,
+	}
+	out.Algo.StorageAddress, err = restrictAddress(db, algo.Permissions, algo.Owner, in.Dataset.Worker, node, algo.StorageAddress)
+	if err != nil {
+		return err
 	}
 	out.TraintupleKey = in.TraintupleKey
 	out.Perf = in.Dataset.Perf
@@ -450,6 +673,154 @@ func getLimitedNbSliceElements(s []string) int {
 	return int(math.Min(float64(len(s)), OutputPageSize))
 }
 
+type outputTupleLease struct {
+	Key       string `json:"key"`
+	Worker    string `json:"worker"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+func (out *outputTupleLease) Fill(key string, in TupleLease) {
+	out.Key = key
+	out.Worker = in.Worker
+	out.ExpiresAt = in.ExpiresAt
+}
+
+// outputTupleLog is the private log of a tuple
+type outputTupleLog struct {
+	Key     string `json:"key"`
+	Worker  string `json:"worker"`
+	Log     string `json:"log"`
+	LogHash string `json:"log_hash"`
+}
+
+type outputCheckPermissions struct {
+	Node                     string                  `json:"node"`
+	Allowed                  bool                    `json:"allowed"`
+	Checks                   []outputPermissionCheck `json:"checks"`
+	OutModelPermissions      *outputPermissionsFull  `json:"out_model_permissions"`
+	OutHeadModelPermissions  *outputPermissionsFull  `json:"out_head_model_permissions"`
+	OutTrunkModelPermissions *outputPermissionsFull  `json:"out_trunk_model_permissions"`
+}
+
+// outputPermissionCheck is the result of one CanProcess check on an asset
+type outputPermissionCheck struct {
+	Node       string     `json:"node"`
+	Key        string     `json:"key"`
+	AssetType  string     `json:"asset_type"`
+	Owner      string     `json:"owner"`
+	Permission Permission `json:"permission"`
+	Allowed    bool       `json:"allowed"`
+}
+
+// Add appends the result of the process check of an asset for the node
+func (out *outputCheckPermissions) Add(db *LedgerDB, key string, assetType AssetType, owner string, permissions Permissions) error {
+	return out.AddForNode(db, out.Node, key, assetType, owner, permissions)
+}
+
+// AddForNode appends the result of the process check of an asset for another
+// node, e.g. the worker of an aggregatetuple
+func (out *outputCheckPermissions) AddForNode(db *LedgerDB, node, key string, assetType AssetType, owner string, permissions Permissions) error {
+	check := outputPermissionCheck{
+		Node:       node,
+		Key:        key,
+		AssetType:  assetType.String(),
+		Owner:      owner,
+		Permission: permissions.Process,
+	}
+	var err error
+	check.Allowed, err = permissions.CanProcess(db, owner, node)
+	if err != nil {
+		return err
+	}
+	if check.Permission.AuthorizedIDs == nil {
+		check.Permission.AuthorizedIDs = []string{}
+	}
+	out.Allowed = out.Allowed && check.Allowed
+	out.Checks = append(out.Checks, check)
+	return nil
+}
+
+type outputNodeGroup struct {
+	Key     string   `json:"key"`
+	Name    string   `json:"name"`
+	Owner   string   `json:"owner"`
+	Members []string `json:"members"`
+}
+
+func (out *outputNodeGroup) Fill(in NodeGroup) {
+	out.Key = in.Key
+	out.Name = in.Name
+	out.Owner = in.Owner
+	out.Members = in.Members
+	if out.Members == nil {
+		out.Members = []string{}
+	}
+}
+
+// outputDataSampleUsage describes a tuple which used a dataSample
+type outputDataSampleUsage struct {
+	Key            string   `json:"key"`
+	Type           string   `json:"type"`
+	Status         string   `json:"status"`
+	ComputePlanKey string   `json:"compute_plan_key"`
+	Rank           int      `json:"rank"`
+	OutModelKeys   []string `json:"out_model_keys"`
+}
+
+func (out *outputDataSampleUsage) Fill(db *LedgerDB, tupleKey string) error {
+	tuple, err := db.GetGenericTuple(tupleKey)
+	if err != nil {
+		return err
+	}
+	out.Key = tupleKey
+	out.Type = tuple.AssetType.String()
+	out.Status = tuple.Status
+	out.ComputePlanKey = tuple.ComputePlanKey
+	out.Rank = tuple.Rank
+	out.OutModelKeys = []string{}
+	switch tuple.AssetType {
+	case TraintupleType:
+		traintuple, err := db.GetTraintuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		if traintuple.OutModel != nil {
+			out.OutModelKeys = append(out.OutModelKeys, traintuple.OutModel.Key)
+		}
+	case CompositeTraintupleType:
+		traintuple, err := db.GetCompositeTraintuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		if traintuple.OutHeadModel.OutModel != nil {
+			out.OutModelKeys = append(out.OutModelKeys, traintuple.OutHeadModel.OutModel.Key)
+		}
+		if traintuple.OutTrunkModel.OutModel != nil {
+			out.OutModelKeys = append(out.OutModelKeys, traintuple.OutTrunkModel.OutModel.Key)
+		}
+	}
+	return nil
+}
+
+type outputModelLineage struct {
+	Key       string                   `json:"key"`
+	Nodes     []outputModelLineageNode `json:"nodes"`
+	Truncated bool                     `json:"truncated"`
+}
+
+// outputModelLineageNode is a tuple of the lineage of a model, its in-models
+// being the edges to its parents
+type outputModelLineageNode struct {
+	Key             string   `json:"key"`
+	Type            string   `json:"type"`
+	Depth           int      `json:"depth"`
+	AlgoKey         string   `json:"algo_key"`
+	DataManagerKey  string   `json:"data_manager_key"`
+	DataSampleCount int      `json:"data_sample_count"`
+	Worker          string   `json:"worker"`
+	InModelKeys     []string `json:"in_model_keys"`
+}
+
 type outputKey struct {
 	Key string `json:"key"`
 }
@@ -22,12 +22,15 @@ type outputAggregatetuple struct {
 	Creator        string                  `json:"creator"`
 	ComputePlanKey string                  `json:"compute_plan_key"`
 	Log            string                  `json:"log"`
+	LogHash        string                  `json:"log_hash,omitempty"`
 	Metadata       map[string]string       `json:"metadata"`
 	InModels       []*Model                `json:"in_models"`
 	OutModel       *KeyChecksumAddress     `json:"out_model"`
+	Progress       *TupleProgress          `json:"progress"`
 	Rank           int                     `json:"rank"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
+	KeepModel      bool                    `json:"keep_model"`
 	Permissions    outputPermissions       `json:"permissions"`
 	Worker         string                  `json:"worker"`
 }
@@ -41,33 +44,59 @@ func (out *outputAggregateAlgo) Fill(in AggregateAlgo) {
 }
 
 // Fill is a method of the receiver outputAggregatetuple. It returns all elements necessary to do a training task from an aggregate trainuple stored in the ledger
-func (outputAggregatetuple *outputAggregatetuple) Fill(db *LedgerDB, traintuple Aggregatetuple) (err error) {
+func (outputAggregatetuple *outputAggregatetuple) Fill(db *LedgerDB, traintuple Aggregatetuple) error {
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	return outputAggregatetuple.fillAs(db, traintuple, node)
+}
+
+// fillAs fills the aggregate tuple as seen by the given node: storage
+// addresses of the assets the node is not allowed to download are left empty
+func (outputAggregatetuple *outputAggregatetuple) fillAs(db *LedgerDB, traintuple Aggregatetuple, node string) (err error) {
 	outputAggregatetuple.Key = traintuple.Key
 	outputAggregatetuple.Creator = traintuple.Creator
 	outputAggregatetuple.Log = traintuple.Log
+	outputAggregatetuple.LogHash = traintuple.LogHash
 	outputAggregatetuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputAggregatetuple.Status = traintuple.Status
 	outputAggregatetuple.Rank = traintuple.Rank
 	outputAggregatetuple.ComputePlanKey = traintuple.ComputePlanKey
-	outputAggregatetuple.OutModel = traintuple.OutModel
+	if traintuple.OutModel != nil {
+		outModel := *traintuple.OutModel
+		outModel.StorageAddress, err = restrictAddress(db, traintuple.Permissions, traintuple.Worker, traintuple.Worker, node, outModel.StorageAddress)
+		if err != nil {
+			return
+		}
+		outputAggregatetuple.OutModel = &outModel
+	}
 	outputAggregatetuple.Tag = traintuple.Tag
+	outputAggregatetuple.KeepModel = traintuple.KeepModel
+	outputAggregatetuple.Progress, err = db.GetTupleProgress(traintuple.Key)
+	if err != nil {
+		return
+	}
 	algo, err := db.GetAggregateAlgo(traintuple.AlgoKey)
 	if err != nil {
 		err = errors.Internal("could not retrieve aggregate algo with key %s - %s", traintuple.AlgoKey, err.Error())
 		return
 	}
 	outputAggregatetuple.Algo = &KeyChecksumAddressName{
-		Key:            algo.Key,
-		Name:           algo.Name,
-		Checksum:       algo.Checksum,
-		StorageAddress: algo.StorageAddress}
+		Key:      algo.Key,
+		Name:     algo.Name,
+		Checksum: algo.Checksum}
+	outputAggregatetuple.Algo.StorageAddress, err = restrictAddress(db, algo.Permissions, algo.Owner, traintuple.Worker, node, algo.StorageAddress)
+	if err != nil {
+		return
+	}
 
 	// fill inModels
 	for _, inModelKey := range traintuple.InModelKeys {
 		if inModelKey == "" {
 			break
 		}
-		keyChecksumAddress, _err := db.GetOutModelKeyChecksumAddress(inModelKey, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType})
+		keyChecksumAddress, _err := db.GetOutModelKeyChecksumAddress(inModelKey, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType})
 		if _err != nil {
 			err = errors.Internal("could not fill in-model with key \"%s\": %s", inModelKey, _err.Error())
 			return
@@ -78,7 +107,10 @@ func (outputAggregatetuple *outputAggregatetuple) Fill(db *LedgerDB, traintuple
 		if keyChecksumAddress != nil {
 			inModel.Key = keyChecksumAddress.Key
 			inModel.Checksum = keyChecksumAddress.Checksum
-			inModel.StorageAddress = keyChecksumAddress.StorageAddress
+			inModel.StorageAddress, err = restrictInModelAddress(db, inModelKey, false, traintuple.Worker, node, keyChecksumAddress.StorageAddress)
+			if err != nil {
+				return
+			}
 		}
 		outputAggregatetuple.InModels = append(outputAggregatetuple.InModels, inModel)
 	}
@@ -29,12 +29,15 @@ type outputCompositeTraintuple struct {
 	InHeadModel    *Model                  `json:"in_head_model"`
 	InTrunkModel   *Model                  `json:"in_trunk_model"`
 	Log            string                  `json:"log"`
+	LogHash        string                  `json:"log_hash,omitempty"`
 	Metadata       map[string]string       `json:"metadata"`
 	OutHeadModel   outHeadModelComposite   `json:"out_head_model"`
 	OutTrunkModel  outModelComposite       `json:"out_trunk_model"`
+	Progress       *TupleProgress          `json:"progress"`
 	Rank           int                     `json:"rank"`
 	Status         string                  `json:"status"`
 	Tag            string                  `json:"tag"`
+	KeepModel      bool                    `json:"keep_model"`
 }
 
 type outHeadModelComposite struct {
@@ -48,11 +51,22 @@ type outModelComposite struct {
 }
 
 //Fill is a method of the receiver outputCompositeTraintuple. It returns all elements necessary to do a training task from a trainuple stored in the ledger
-func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, traintuple CompositeTraintuple) (err error) {
+func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, traintuple CompositeTraintuple) error {
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return err
+	}
+	return outputCompositeTraintuple.fillAs(db, traintuple, node)
+}
+
+// fillAs fills the composite traintuple as seen by the given node: storage
+// addresses of the assets the node is not allowed to download are left empty
+func (outputCompositeTraintuple *outputCompositeTraintuple) fillAs(db *LedgerDB, traintuple CompositeTraintuple, node string) (err error) {
 
 	outputCompositeTraintuple.Key = traintuple.Key
 	outputCompositeTraintuple.Creator = traintuple.Creator
 	outputCompositeTraintuple.Log = traintuple.Log
+	outputCompositeTraintuple.LogHash = traintuple.LogHash
 	outputCompositeTraintuple.Metadata = initMapOutput(traintuple.Metadata)
 	outputCompositeTraintuple.Status = traintuple.Status
 	outputCompositeTraintuple.Rank = traintuple.Rank
@@ -61,9 +75,21 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 		OutModel:    traintuple.OutHeadModel.OutModel,
 		Permissions: getOutPermissions(traintuple.OutHeadModel.Permissions)}
 	outputCompositeTraintuple.OutTrunkModel = outModelComposite{
-		OutModel:    traintuple.OutTrunkModel.OutModel,
 		Permissions: getOutPermissions(traintuple.OutTrunkModel.Permissions)}
+	if traintuple.OutTrunkModel.OutModel != nil {
+		outTrunkModel := *traintuple.OutTrunkModel.OutModel
+		outTrunkModel.StorageAddress, err = restrictAddress(db, traintuple.OutTrunkModel.Permissions, traintuple.Dataset.Worker, traintuple.Dataset.Worker, node, outTrunkModel.StorageAddress)
+		if err != nil {
+			return
+		}
+		outputCompositeTraintuple.OutTrunkModel.OutModel = &outTrunkModel
+	}
 	outputCompositeTraintuple.Tag = traintuple.Tag
+	outputCompositeTraintuple.KeepModel = traintuple.KeepModel
+	outputCompositeTraintuple.Progress, err = db.GetTupleProgress(traintuple.Key)
+	if err != nil {
+		return
+	}
 	// fill algo
 	algo, err := db.GetCompositeAlgo(traintuple.AlgoKey)
 	if err != nil {
@@ -71,10 +97,13 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 		return
 	}
 	outputCompositeTraintuple.Algo = &KeyChecksumAddressName{
-		Key:            algo.Key,
-		Name:           algo.Name,
-		Checksum:       algo.Checksum,
-		StorageAddress: algo.StorageAddress}
+		Key:      algo.Key,
+		Name:     algo.Name,
+		Checksum: algo.Checksum}
+	outputCompositeTraintuple.Algo.StorageAddress, err = restrictAddress(db, algo.Permissions, algo.Owner, traintuple.Dataset.Worker, node, algo.StorageAddress)
+	if err != nil {
+		return
+	}
 
 	// fill in-model (head)
 	if traintuple.InHeadModel != "" {
@@ -99,7 +128,8 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 		// - a traintuple's out model
 		// - a composite traintuple's head out model
 		// - an aggregate tuple's out model
-		outModel, _err := db.GetOutModelKeyChecksumAddress(traintuple.InTrunkModel, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType})
+		// - a registered external model
+		outModel, _err := db.GetOutModelKeyChecksumAddress(traintuple.InTrunkModel, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType})
 		if _err != nil {
 			err = errors.Internal("could not fill (trunk) in-model with key \"%s\": %s", traintuple.InTrunkModel, _err.Error())
 			return
@@ -110,7 +140,10 @@ func (outputCompositeTraintuple *outputCompositeTraintuple) Fill(db *LedgerDB, t
 		if outModel != nil {
 			outputCompositeTraintuple.InTrunkModel.Key = outModel.Key
 			outputCompositeTraintuple.InTrunkModel.Checksum = outModel.Checksum
-			outputCompositeTraintuple.InTrunkModel.StorageAddress = outModel.StorageAddress
+			outputCompositeTraintuple.InTrunkModel.StorageAddress, err = restrictInModelAddress(db, traintuple.InTrunkModel, false, traintuple.Dataset.Worker, node, outModel.StorageAddress)
+			if err != nil {
+				return
+			}
 		}
 	}
 
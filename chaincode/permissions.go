@@ -14,7 +14,11 @@
 
 package main
 
-import "chaincode/errors"
+import (
+	"chaincode/errors"
+	"fmt"
+	"strings"
+)
 
 // Permission represents one permission based on an action type
 type Permission struct {
@@ -34,41 +38,77 @@ type Permissions struct {
 }
 
 // CanProcess checks if a node can process the asset with the current permissions
-func (perms Permissions) CanProcess(owner, node string) bool {
+func (perms Permissions) CanProcess(db *LedgerDB, owner, node string) (bool, error) {
+	return perms.Process.allows(db, owner, node)
+}
+
+// CanDownload checks if a node can download the asset with the current permissions
+func (perms Permissions) CanDownload(db *LedgerDB, owner, node string) (bool, error) {
+	return perms.Download.allows(db, owner, node)
+}
+
+func (priv Permission) allows(db *LedgerDB, owner, node string) (bool, error) {
 	if owner == node {
-		return true
+		return true, nil
 	}
 
-	if perms.Process.Public {
-		return true
+	if priv.Public {
+		return true, nil
 	}
 
-	for _, authorizedNode := range perms.Process.AuthorizedIDs {
-		if node == authorizedNode {
-			return true
+	return priv.grants(db, node)
+}
+
+// grants checks if a node is listed in the authorized IDs, either directly or
+// as a member of one of the listed node groups
+func (priv Permission) grants(db *LedgerDB, node string) (bool, error) {
+	for _, authorizedID := range priv.AuthorizedIDs {
+		if node == authorizedID {
+			return true, nil
+		}
+		groupKey, ok := parseNodeGroupRef(authorizedID)
+		if !ok {
+			continue
+		}
+		member, err := isNodeGroupMember(db, groupKey, node)
+		if err != nil {
+			return false, unknownNodeGroupError(err, groupKey)
+		}
+		if member {
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
 // NewPermissions create the Permissions according to the arg received
 func NewPermissions(db *LedgerDB, in inputPermissions) (Permissions, error) {
+	owner, err := GetTxCreator(db.cc)
+	if err != nil {
+		return Permissions{}, err
+	}
+	return newPermissions(db, in, owner)
+}
+
+func newPermissions(db *LedgerDB, in inputPermissions, owner string) (Permissions, error) {
 	if !in.Process.Public {
 		if err := validateAuthorizedIds(db, in.Process.AuthorizedIDs); err != nil {
 			return Permissions{}, err
 		}
 	}
-
-	owner, err := GetTxCreator(db.cc)
-	if err != nil {
-		return Permissions{}, err
+	if in.Download != nil && !in.Download.Public {
+		if err := validateAuthorizedIds(db, in.Download.AuthorizedIDs); err != nil {
+			return Permissions{}, err
+		}
 	}
 
 	permissions := Permissions{}
-	process := newPermission(in.Process, owner)
-	permissions.Process = process
-	// Download permission is not implemented in the node server, so it is set to the process permission
-	permissions.Download = process
+	permissions.Process = newPermission(in.Process, owner)
+	// When omitted, the download permission is set to the process permission
+	permissions.Download = permissions.Process
+	if in.Download != nil {
+		permissions.Download = newPermission(*in.Download, owner)
+	}
 	return permissions, nil
 }
 
@@ -81,55 +121,102 @@ func newPermission(in inputPermission, owner string) Permission {
 	return Permission(in)
 }
 
-func (priv Permission) include(other Permission) bool {
+func (priv Permission) include(db *LedgerDB, other Permission) (bool, error) {
 	if priv.Public {
-		return true
+		return true, nil
 	}
 	if other.Public {
-		return false
+		return false, nil
 	}
-	for _, node := range other.AuthorizedIDs {
-		if !stringInSlice(node, priv.AuthorizedIDs) {
-			return false
+	for _, authorizedID := range other.AuthorizedIDs {
+		if stringInSlice(authorizedID, priv.AuthorizedIDs) {
+			continue
+		}
+		nodes := []string{authorizedID}
+		if groupKey, ok := parseNodeGroupRef(authorizedID); ok {
+			// a group is included if all its current members are
+			members, err := getNodeGroupMembers(db, groupKey)
+			if err != nil {
+				return false, unknownNodeGroupError(err, groupKey)
+			}
+			nodes = members
+		}
+		for _, node := range nodes {
+			granted, err := priv.grants(db, node)
+			if err != nil || !granted {
+				return false, err
+			}
 		}
 	}
-	return true
+	return true, nil
 }
 
 // MergePermissions returns the intersection of input permissions
-func MergePermissions(x, y Permissions) Permissions {
+func MergePermissions(db *LedgerDB, x, y Permissions) (Permissions, error) {
 	perm := Permissions{}
-	perm.Process = mergePermissions(x.Process, y.Process)
-	perm.Download = mergePermissions(x.Download, y.Download)
-	return perm
+	var err error
+	if perm.Process, err = mergePermissions(db, x.Process, y.Process); err != nil {
+		return perm, err
+	}
+	perm.Download, err = mergePermissions(db, x.Download, y.Download)
+	return perm, err
 }
 
-func mergePermissions(x, y Permission) Permission {
+func mergePermissions(db *LedgerDB, x, y Permission) (Permission, error) {
 	priv := Permission{}
 	priv.Public = x.Public && y.Public
 
+	var err error
 	switch {
 	case !x.Public && y.Public:
 		priv.AuthorizedIDs = x.AuthorizedIDs
 	case x.Public && !y.Public:
 		priv.AuthorizedIDs = y.AuthorizedIDs
 	default:
-		priv.AuthorizedIDs = x.getNodesIntersection(y)
+		priv.AuthorizedIDs, err = x.getNodesIntersection(db, y)
 	}
-	return priv
+	return priv, err
 }
 
-func (priv Permission) getNodesIntersection(p Permission) []string {
+// getNodesIntersection returns the authorized IDs common to both permissions.
+// A node group listed in both is kept as a reference, so that its membership
+// is resolved at check time. A node group listed in only one of them is
+// replaced by its current members granted by the other one.
+func (priv Permission) getNodesIntersection(db *LedgerDB, p Permission) ([]string, error) {
 	nodes := []string{}
-	for _, i := range priv.AuthorizedIDs {
-		for _, j := range p.AuthorizedIDs {
-			if i == j {
-				nodes = append(nodes, i)
-				break
+	add := func(IDs ...string) {
+		for _, ID := range IDs {
+			if !stringInSlice(ID, nodes) {
+				nodes = append(nodes, ID)
+			}
+		}
+	}
+	for _, x := range [][2]Permission{{priv, p}, {p, priv}} {
+		for _, authorizedID := range x[0].AuthorizedIDs {
+			if stringInSlice(authorizedID, x[1].AuthorizedIDs) {
+				add(authorizedID)
+				continue
+			}
+			IDs := []string{authorizedID}
+			if groupKey, ok := parseNodeGroupRef(authorizedID); ok {
+				members, err := getNodeGroupMembers(db, groupKey)
+				if err != nil {
+					return nil, unknownNodeGroupError(err, groupKey)
+				}
+				IDs = members
+			}
+			for _, node := range IDs {
+				granted, err := x[1].grants(db, node)
+				if err != nil {
+					return nil, err
+				}
+				if granted {
+					add(node)
+				}
 			}
 		}
 	}
-	return nodes
+	return nodes, nil
 }
 
 // validateAuthorizedIds will return an error if one of the provided IDs is not a valid node
@@ -141,12 +228,19 @@ func validateAuthorizedIds(db *LedgerDB, IDs []string) error {
 
 	nodesIDs := []string{}
 	for _, node := range nodes {
+		if node.Inactive {
+			continue
+		}
 		nodesIDs = append(nodesIDs, node.ID)
 	}
 
-	// Validate Process inputPermissions
-	// @TODO Validate Download inputPermissions when implemented
 	for _, authorizedID := range IDs {
+		if groupKey, ok := parseNodeGroupRef(authorizedID); ok {
+			if _, err := db.GetNodeGroup(groupKey); err != nil {
+				return unknownNodeGroupError(err, groupKey)
+			}
+			continue
+		}
 		if !stringInSlice(authorizedID, nodesIDs) {
 			return errors.BadRequest("invalid permission input values")
 		}
@@ -154,3 +248,188 @@ func validateAuthorizedIds(db *LedgerDB, IDs []string) error {
 
 	return nil
 }
+
+// updatePermissions replaces the permissions of an algo, a composite algo, an
+// aggregate algo, an objective or a data manager. Only the asset owner can
+// update them, and the previous permissions are recorded in the asset history.
+// Tuples keep the permissions merged at their creation.
+func updatePermissions(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputUpdatePermissions{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	assetType, err := db.GetAssetType(inp.Key)
+	if err != nil {
+		return
+	}
+
+	var asset interface{}
+	var owner string
+	var permissions *Permissions
+	switch assetType {
+	case AlgoType:
+		algo, err := db.GetAlgo(inp.Key)
+		if err != nil {
+			return resp, err
+		}
+		asset, owner, permissions = &algo, algo.Owner, &algo.Permissions
+	case CompositeAlgoType:
+		algo, err := db.GetCompositeAlgo(inp.Key)
+		if err != nil {
+			return resp, err
+		}
+		asset, owner, permissions = &algo, algo.Owner, &algo.Permissions
+	case AggregateAlgoType:
+		algo, err := db.GetAggregateAlgo(inp.Key)
+		if err != nil {
+			return resp, err
+		}
+		asset, owner, permissions = &algo, algo.Owner, &algo.Permissions
+	case ObjectiveType:
+		objective, err := db.GetObjective(inp.Key)
+		if err != nil {
+			return resp, err
+		}
+		asset, owner, permissions = &objective, objective.Owner, &objective.Permissions
+	case DataManagerType:
+		dataManager, err := db.GetDataManager(inp.Key)
+		if err != nil {
+			return resp, err
+		}
+		asset, owner, permissions = &dataManager, dataManager.Owner, &dataManager.Permissions
+	default:
+		return resp, errors.BadRequest("cannot update the permissions of %s %s", assetType, inp.Key)
+	}
+
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != owner {
+		return resp, errors.Forbidden("%s is not allowed to update the permissions of %s", txCreator, inp.Key)
+	}
+	newPermissions, err := NewPermissions(db, inp.Permissions)
+	if err != nil {
+		return
+	}
+	now, err := GetTxTime(db.cc)
+	if err != nil {
+		return
+	}
+
+	history, err := db.GetPermissionsHistory(inp.Key)
+	if err != nil {
+		return
+	}
+	history = append(history, PermissionsUpdate{
+		Previous:    *permissions,
+		Permissions: newPermissions,
+		UpdatedBy:   txCreator,
+		UpdatedAt:   now.Unix(),
+	})
+	if err = db.Put(getPermissionsHistoryKey(inp.Key), history); err != nil {
+		return
+	}
+
+	*permissions = newPermissions
+	if err = db.Put(inp.Key, asset); err != nil {
+		return
+	}
+	return outputKey{Key: inp.Key}, nil
+}
+
+// queryPermissionsHistory returns the permissions updates of an asset, oldest first
+func queryPermissionsHistory(db *LedgerDB, args []string) (resp []PermissionsUpdate, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	if _, err = db.GetAssetType(inp.Key); err != nil {
+		return
+	}
+	return db.GetPermissionsHistory(inp.Key)
+}
+
+// getPermissionsHistoryKey returns the permissions history key for a given asset
+func getPermissionsHistoryKey(assetKey string) string {
+	return fmt.Sprintf("permissions~%v~history", assetKey)
+}
+
+// getOutModelPermissions returns the permissions of the out-model of a tuple,
+// along with the model owner, the worker of the tuple, and the tuple type. For
+// a composite traintuple, the head or trunk out-model is selected by the head
+// argument.
+func getOutModelPermissions(db *LedgerDB, tupleKey string, head bool) (Permissions, string, AssetType, error) {
+	assetType, err := db.GetAssetType(tupleKey)
+	if err != nil {
+		return Permissions{}, "", assetType, errors.BadRequest(err, "key %s is not a valid asset", tupleKey)
+	}
+	switch assetType {
+	case TraintupleType:
+		tuple, err := db.GetTraintuple(tupleKey)
+		if err != nil {
+			return Permissions{}, "", assetType, errors.BadRequest(err, "could not retrieve traintuple with key %s", tupleKey)
+		}
+		return tuple.Permissions, tuple.Dataset.Worker, assetType, nil
+	case CompositeTraintupleType:
+		tuple, err := db.GetCompositeTraintuple(tupleKey)
+		if err != nil {
+			return Permissions{}, "", assetType, errors.BadRequest(err, "could not retrieve composite traintuple with key %s", tupleKey)
+		}
+		if head {
+			return tuple.OutHeadModel.Permissions, tuple.Dataset.Worker, assetType, nil
+		}
+		return tuple.OutTrunkModel.Permissions, tuple.Dataset.Worker, assetType, nil
+	case AggregatetupleType:
+		tuple, err := db.GetAggregatetuple(tupleKey)
+		if err != nil {
+			return Permissions{}, "", assetType, errors.BadRequest(err, "could not retrieve aggregatetuple with key %s", tupleKey)
+		}
+		return tuple.Permissions, tuple.Worker, assetType, nil
+	case ExternalModelType:
+		model, err := db.GetExternalModel(tupleKey)
+		if err != nil {
+			return Permissions{}, "", assetType, errors.BadRequest(err, "could not retrieve external model with key %s", tupleKey)
+		}
+		return model.Permissions, model.Owner, assetType, nil
+	default:
+		return Permissions{}, "", assetType, errors.BadRequest("key %s is not a valid traintuple", tupleKey)
+	}
+}
+
+// checkInModelsPermissions returns a Forbidden error listing the in-models
+// whose out-model the worker is not authorized to process. For composite
+// traintuples, the head or trunk out-model is selected by the head argument.
+func checkInModelsPermissions(db *LedgerDB, worker string, inModelKeys []string, head bool) error {
+	forbiddenKeys := []string{}
+	for _, inModelKey := range inModelKeys {
+		allowed, err := canProcessInModel(db, worker, inModelKey, head)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			forbiddenKeys = append(forbiddenKeys, inModelKey)
+		}
+	}
+	return forbiddenInModelsError(worker, forbiddenKeys)
+}
+
+// canProcessInModel checks if the worker can process the out-model of a tuple
+func canProcessInModel(db *LedgerDB, worker string, inModelKey string, head bool) (bool, error) {
+	permissions, owner, _, err := getOutModelPermissions(db, inModelKey, head)
+	if err != nil {
+		return false, err
+	}
+	return permissions.CanProcess(db, owner, worker)
+}
+
+// forbiddenInModelsError returns a Forbidden error listing the in-models keys,
+// or nil if there is none
+func forbiddenInModelsError(worker string, forbiddenKeys []string) error {
+	if len(forbiddenKeys) == 0 {
+		return nil
+	}
+	return errors.Forbidden("worker %s is not authorized to process in-models %s", worker, strings.Join(forbiddenKeys, ", "))
+}
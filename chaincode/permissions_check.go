@@ -0,0 +1,191 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import "chaincode/errors"
+
+// checkPermissions evaluates, for a prospective tuple, every process check
+// performed on its registration against the given node, and previews the
+// permissions of the resulting out-models. It doesn't check anything else
+// (data samples, compute plan, status of the in-models...).
+func checkPermissions(db *LedgerDB, args []string) (resp outputCheckPermissions, err error) {
+	inp := inputCheckPermissions{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	resp = outputCheckPermissions{Node: inp.Node, Allowed: true, Checks: []outputPermissionCheck{}}
+
+	switch inp.Type {
+	case TraintupleType.String():
+		err = checkTraintuplePermissions(db, inp, &resp)
+	case CompositeTraintupleType.String():
+		err = checkCompositeTraintuplePermissions(db, inp, &resp)
+	case AggregatetupleType.String():
+		err = checkAggregatetuplePermissions(db, inp, &resp)
+	case TesttupleType.String():
+		err = checkTesttuplePermissions(db, inp, &resp)
+	}
+	return
+}
+
+func checkTraintuplePermissions(db *LedgerDB, inp inputCheckPermissions, resp *outputCheckPermissions) error {
+	if inp.AlgoKey == "" || inp.DataManagerKey == "" {
+		return errors.BadRequest("algo_key and data_manager_key are required to check the permissions of a traintuple")
+	}
+	algo, err := db.GetAlgo(inp.AlgoKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
+	}
+	if err = resp.Add(db, inp.AlgoKey, AlgoType, algo.Owner, algo.Permissions); err != nil {
+		return err
+	}
+
+	dataManager, err := db.GetDataManager(inp.DataManagerKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
+	}
+	if err = resp.Add(db, inp.DataManagerKey, DataManagerType, dataManager.Owner, dataManager.Permissions); err != nil {
+		return err
+	}
+
+	// the worker, where the data belong, must be able to process the in-models
+	for _, inModelKey := range inp.InModels {
+		if err := addInModelCheck(db, resp, dataManager.Owner, inModelKey, false); err != nil {
+			return err
+		}
+	}
+
+	permissions, err := MergePermissions(db, dataManager.Permissions, algo.Permissions)
+	if err != nil {
+		return err
+	}
+	out := outputPermissionsFull{}
+	out.Fill(permissions)
+	resp.OutModelPermissions = &out
+	return nil
+}
+
+func checkCompositeTraintuplePermissions(db *LedgerDB, inp inputCheckPermissions, resp *outputCheckPermissions) error {
+	if inp.AlgoKey == "" || inp.DataManagerKey == "" {
+		return errors.BadRequest("algo_key and data_manager_key are required to check the permissions of a composite traintuple")
+	}
+	algo, err := db.GetCompositeAlgo(inp.AlgoKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve Composite algo with key %s", inp.AlgoKey)
+	}
+	if err = resp.Add(db, inp.AlgoKey, CompositeAlgoType, algo.Owner, algo.Permissions); err != nil {
+		return err
+	}
+
+	dataManager, err := db.GetDataManager(inp.DataManagerKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
+	}
+	if err = resp.Add(db, inp.DataManagerKey, DataManagerType, dataManager.Owner, dataManager.Permissions); err != nil {
+		return err
+	}
+
+	// the worker, where the data belong, must be able to process the in-models
+	if inp.InHeadModelKey != "" {
+		if err := addInModelCheck(db, resp, dataManager.Owner, inp.InHeadModelKey, true); err != nil {
+			return err
+		}
+	}
+	if inp.InTrunkModelKey != "" {
+		if err := addInModelCheck(db, resp, dataManager.Owner, inp.InTrunkModelKey, false); err != nil {
+			return err
+		}
+	}
+
+	// the head out-model stays on the worker where the data belong
+	workerOnly := Permission{Public: false, AuthorizedIDs: []string{dataManager.Owner}}
+	head := outputPermissionsFull{}
+	head.Fill(Permissions{Process: workerOnly, Download: workerOnly})
+	resp.OutHeadModelPermissions = &head
+
+	if inp.OutTrunkModelPermissions != nil {
+		permissions, err := newPermissions(db, *inp.OutTrunkModelPermissions, inp.Node)
+		if err != nil {
+			return err
+		}
+		trunk := outputPermissionsFull{}
+		trunk.Fill(permissions)
+		resp.OutTrunkModelPermissions = &trunk
+	}
+	return nil
+}
+
+func checkAggregatetuplePermissions(db *LedgerDB, inp inputCheckPermissions, resp *outputCheckPermissions) error {
+	if inp.AlgoKey == "" {
+		return errors.BadRequest("algo_key is required to check the permissions of an aggregatetuple")
+	}
+	algo, err := db.GetAggregateAlgo(inp.AlgoKey)
+	if err != nil {
+		return errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
+	}
+	if err = resp.Add(db, inp.AlgoKey, AggregateAlgoType, algo.Owner, algo.Permissions); err != nil {
+		return err
+	}
+
+	permissions, err := newPermissions(db, OpenPermissions, inp.Node)
+	if err != nil {
+		return err
+	}
+	for _, inModelKey := range inp.InModels {
+		// the trunk out-model is used when the parent is a composite traintuple
+		parentPermissions, _, _, err := getOutModelPermissions(db, inModelKey, false)
+		if err != nil {
+			return err
+		}
+		if inp.Worker != "" {
+			if err := addInModelCheck(db, resp, inp.Worker, inModelKey, false); err != nil {
+				return err
+			}
+		}
+		permissions, err = MergePermissions(db, permissions, parentPermissions)
+		if err != nil {
+			return err
+		}
+	}
+	out := outputPermissionsFull{}
+	out.Fill(permissions)
+	resp.OutModelPermissions = &out
+	return nil
+}
+
+func checkTesttuplePermissions(db *LedgerDB, inp inputCheckPermissions, resp *outputCheckPermissions) error {
+	if inp.TraintupleKey == "" {
+		return errors.BadRequest("traintuple_key is required to check the permissions of a testtuple")
+	}
+	// the head out-model is tested when the traintuple is a composite one
+	permissions, owner, assetType, err := getOutModelPermissions(db, inp.TraintupleKey, true)
+	if err != nil {
+		return err
+	}
+	if err = resp.Add(db, inp.TraintupleKey, assetType, owner, permissions); err != nil {
+		return err
+	}
+	return nil
+}
+
+// addInModelCheck appends the process check of the out-model of an in-model for the worker
+func addInModelCheck(db *LedgerDB, resp *outputCheckPermissions, worker, inModelKey string, head bool) error {
+	permissions, owner, assetType, err := getOutModelPermissions(db, inModelKey, head)
+	if err != nil {
+		return err
+	}
+	return resp.AddForNode(db, worker, inModelKey, assetType, owner, permissions)
+}
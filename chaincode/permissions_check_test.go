@@ -0,0 +1,106 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckPermissions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := updatePermissions(db, assetToArgs(inputUpdatePermissions{
+		Key:         algoKey,
+		Permissions: inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}},
+	}))
+	require.NoError(t, err)
+
+	inp := inputCheckPermissions{
+		Node:           workerB,
+		Type:           TraintupleType.String(),
+		AlgoKey:        algoKey,
+		DataManagerKey: dataManagerKey,
+	}
+	resp, err := checkPermissions(db, assetToArgs(inp))
+	require.NoError(t, err)
+	assert.False(t, resp.Allowed)
+	require.Len(t, resp.Checks, 2)
+	assert.Equal(t, outputPermissionCheck{
+		Node:       workerB,
+		Key:        algoKey,
+		AssetType:  "algo",
+		Owner:      workerA,
+		Permission: Permission{Public: false, AuthorizedIDs: []string{workerA}},
+		Allowed:    false,
+	}, resp.Checks[0])
+	assert.True(t, resp.Checks[1].Allowed, "the data manager is public")
+	require.NotNil(t, resp.OutModelPermissions)
+	assert.Equal(t, []string{workerA}, resp.OutModelPermissions.Process.AuthorizedIDs)
+
+	inp.Node = workerA
+	resp, err = checkPermissions(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	assert.True(t, resp.Allowed, "the owner can always process its assets")
+
+	resp, err = checkPermissions(db, assetToArgs(inputCheckPermissions{
+		Node:          workerB,
+		Type:          TesttupleType.String(),
+		TraintupleKey: traintupleKey,
+	}))
+	assert.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	require.Len(t, resp.Checks, 1)
+	assert.Equal(t, "traintuple", resp.Checks[0].AssetType)
+
+	resp, err = checkPermissions(db, assetToArgs(inputCheckPermissions{
+		Node:     workerB,
+		Type:     AggregatetupleType.String(),
+		AlgoKey:  aggregateAlgoKey,
+		InModels: []string{traintupleKey},
+		Worker:   workerB,
+	}))
+	assert.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	require.Len(t, resp.Checks, 2)
+	assert.Equal(t, workerB, resp.Checks[1].Node)
+	assert.Equal(t, traintupleKey, resp.Checks[1].Key)
+	require.NotNil(t, resp.OutModelPermissions)
+	assert.True(t, resp.OutModelPermissions.Process.Public)
+
+	resp, err = checkPermissions(db, assetToArgs(inputCheckPermissions{
+		Node:                     workerB,
+		Type:                     CompositeTraintupleType.String(),
+		AlgoKey:                  compositeAlgoKey,
+		DataManagerKey:           dataManagerKey,
+		OutTrunkModelPermissions: &inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{workerA}}},
+	}))
+	assert.NoError(t, err)
+	assert.True(t, resp.Allowed)
+	require.NotNil(t, resp.OutHeadModelPermissions)
+	assert.Equal(t, []string{workerA}, resp.OutHeadModelPermissions.Process.AuthorizedIDs)
+	require.NotNil(t, resp.OutTrunkModelPermissions)
+	assert.Equal(t, []string{workerB, workerA}, resp.OutTrunkModelPermissions.Process.AuthorizedIDs)
+
+	_, err = checkPermissions(db, assetToArgs(inputCheckPermissions{Node: workerB, Type: TraintupleType.String()}))
+	assert.Error(t, err, "algo and data manager keys are required for a traintuple")
+}
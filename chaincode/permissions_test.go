@@ -15,9 +15,12 @@
 package main
 
 import (
+	"chaincode/errors"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -31,7 +34,24 @@ var (
 	defaultOwner = "me"
 )
 
+// canProcess is CanProcess for the tests, which fails on an error
+func canProcess(t *testing.T, db *LedgerDB, perms Permissions, owner, node string) bool {
+	allowed, err := perms.CanProcess(db, owner, node)
+	require.NoError(t, err)
+	return allowed
+}
+
+// canDownload is CanDownload for the tests, which fails on an error
+func canDownload(t *testing.T, db *LedgerDB, perms Permissions, owner, node string) bool {
+	allowed, err := perms.CanDownload(db, owner, node)
+	require.NoError(t, err)
+	return allowed
+}
+
 func TestPermissionsCanProcess(t *testing.T) {
+	mockStub := NewMockStubWithRegisterNode("substra", new(SubstraChaincode))
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
 	perms := defaultPermissions
 
 	testTable := []struct {
@@ -51,13 +71,67 @@ func TestPermissionsCanProcess(t *testing.T) {
 			perms.Process.Public = test.public
 			perms.Process.AuthorizedIDs = test.authorizedIDs
 
-			access := perms.CanProcess(defaultOwner, test.node)
+			access := canProcess(t, db, perms, defaultOwner, test.node)
 			assert.Equal(t, test.expectedAccess, access)
 		})
 	}
 }
 
+func TestPermissionsCanDownload(t *testing.T) {
+	mockStub := NewMockStubWithRegisterNode("substra", new(SubstraChaincode))
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+	perms := Permissions{
+		Process:  Permission{Public: true},
+		Download: Permission{Public: false, AuthorizedIDs: []string{"foo"}},
+	}
+
+	assert.True(t, canProcess(t, db, perms, defaultOwner, "baz"))
+	assert.True(t, canDownload(t, db, perms, defaultOwner, defaultOwner), "the owner can always download")
+	assert.True(t, canDownload(t, db, perms, defaultOwner, "foo"))
+	assert.False(t, canDownload(t, db, perms, defaultOwner, "baz"), "process permission doesn't grant download")
+}
+
+func TestNewPermissionsDownload(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	perms, err := NewPermissions(db, OpenPermissions)
+	assert.NoError(t, err)
+	assert.Equal(t, perms.Process, perms.Download, "download should default to process")
+
+	inp := inputPermissions{
+		Process:  inputPermission{Public: true, AuthorizedIDs: []string{}},
+		Download: &inputPermission{Public: false, AuthorizedIDs: []string{workerB}},
+	}
+	perms, err = NewPermissions(db, inp)
+	assert.NoError(t, err)
+	assert.True(t, perms.Process.Public)
+	assert.False(t, perms.Download.Public)
+	assert.Equal(t, []string{workerA, workerB}, perms.Download.AuthorizedIDs)
+
+	inp.Download.AuthorizedIDs = []string{"unknown"}
+	_, err = NewPermissions(db, inp)
+	assert.Error(t, err, "download authorized IDs should be registered nodes")
+
+	algo := Algo{
+		Owner:          workerB,
+		StorageAddress: "https://toto/algo/222/algo",
+		Permissions:    Permissions{Process: Permission{Public: true}, Download: Permission{AuthorizedIDs: []string{workerB}}},
+	}
+	out := outputAlgo{}
+	out.Fill(algo)
+	require.NoError(t, out.restrictDownload(db, algo, workerA))
+	assert.Empty(t, out.Content.StorageAddress, "the algo address should be hidden from a node without download permission")
+}
+
 func TestPrivInclusion(t *testing.T) {
+	mockStub := NewMockStubWithRegisterNode("substra", new(SubstraChaincode))
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
 	testTable := []struct {
 		name             string
 		includedOpenbar  bool
@@ -78,12 +152,17 @@ func TestPrivInclusion(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			privIncluded := Permission{Public: test.includedOpenbar, AuthorizedIDs: test.includedNodes}
 			privIncluding := Permission{Public: test.includingOpenbar, AuthorizedIDs: test.includingNodes}
-			assert.Equal(t, test.doesInclude, privIncluding.include(privIncluded))
+			included, err := privIncluding.include(db, privIncluded)
+			require.NoError(t, err)
+			assert.Equal(t, test.doesInclude, included)
 		})
 	}
 }
 
 func TestMergingMechanism(t *testing.T) {
+	mockStub := NewMockStubWithRegisterNode("substra", new(SubstraChaincode))
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
 	testTable := []struct {
 		name        string
 		toMergeINR  bool
@@ -103,19 +182,197 @@ func TestMergingMechanism(t *testing.T) {
 				Public:        test.toMergeINR,
 				AuthorizedIDs: test.toMergeRU,
 			}
-			mergedPriv := mergePermissions(defaultPermission, toMerge)
+			mergedPriv, err := mergePermissions(db, defaultPermission, toMerge)
+			require.NoError(t, err)
 			assert.Equal(t, test.expectedINR, mergedPriv.Public)
 			assert.ElementsMatch(t, test.expectedRU, mergedPriv.AuthorizedIDs)
-			privMerged := mergePermissions(toMerge, defaultPermission)
+			privMerged, err := mergePermissions(db, toMerge, defaultPermission)
+			require.NoError(t, err)
 			assert.Equal(t, mergedPriv.Public, privMerged.Public, "merging should be transitif")
 			assert.ElementsMatch(t, mergedPriv.AuthorizedIDs, privMerged.AuthorizedIDs, "merging should be transitif")
 
-			theSamePriv := mergePermissions(Permission{Public: true}, toMerge)
+			theSamePriv, err := mergePermissions(db, Permission{Public: true}, toMerge)
+			require.NoError(t, err)
 			assert.Equal(t, toMerge.Public, theSamePriv.Public, "a non restrictive permission should be neutral")
 			assert.ElementsMatch(t, toMerge.AuthorizedIDs, theSamePriv.AuthorizedIDs, "a non restrictive permission should be neutral")
-			theSamePriv = mergePermissions(toMerge, Permission{Public: true})
+			theSamePriv, err = mergePermissions(db, toMerge, Permission{Public: true})
+			require.NoError(t, err)
 			assert.Equal(t, toMerge.Public, theSamePriv.Public, "neutral element should be transitive")
 			assert.ElementsMatch(t, toMerge.AuthorizedIDs, theSamePriv.AuthorizedIDs, "neutral element should be transitive")
 		})
 	}
 }
+
+func TestUpdatePermissions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputUpdatePermissions{
+		Key: algoKey,
+		Permissions: inputPermissions{
+			Process: inputPermission{Public: false, AuthorizedIDs: []string{workerB}},
+		},
+	}
+	_, err := updatePermissions(db, assetToArgs(inp))
+	assert.NoError(t, err)
+
+	algo, err := db.GetAlgo(algoKey)
+	assert.NoError(t, err)
+	assert.False(t, algo.Permissions.Process.Public)
+	assert.Equal(t, []string{workerA, workerB}, algo.Permissions.Process.AuthorizedIDs)
+
+	history, err := queryPermissionsHistory(db, keyToArgs(algoKey))
+	assert.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.True(t, history[0].Previous.Process.Public)
+	assert.Equal(t, algo.Permissions, history[0].Permissions)
+	assert.Equal(t, workerA, history[0].UpdatedBy)
+
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	assert.NoError(t, err)
+	assert.True(t, traintuple.Permissions.Process.Public, "existing tuples should keep their permissions")
+
+	for _, key := range []string{dataManagerKey, objectiveKey} {
+		inp.Key = key
+		_, err = updatePermissions(db, assetToArgs(inp))
+		assert.NoError(t, err, "owner should be able to update the permissions of %s", key)
+	}
+
+	inp.Key = algoKey
+	inp.Permissions.Process.AuthorizedIDs = []string{"unknown"}
+	_, err = updatePermissions(db, assetToArgs(inp))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "authorized IDs should be registered nodes")
+
+	inp.Key = traintupleKey
+	inp.Permissions = OpenPermissions
+	_, err = updatePermissions(db, assetToArgs(inp))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "tuple permissions cannot be updated")
+
+	mockStub.Creator = workerB
+	inp.Key = algoKey
+	_, err = updatePermissions(db, assetToArgs(inp))
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode(), "only the owner can update permissions")
+}
+
+func TestTupleOutputsRestrictDownload(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// the algos stay processable by everyone but only their owner can
+	// download them, which the tuples inherit
+	for _, key := range []string{algoKey, compositeAlgoKey, aggregateAlgoKey} {
+		inp := inputUpdatePermissions{
+			Key: key,
+			Permissions: inputPermissions{
+				Process:  inputPermission{Public: true, AuthorizedIDs: []string{}},
+				Download: &inputPermission{Public: false, AuthorizedIDs: []string{}},
+			},
+		}
+		_, err := updatePermissions(db, assetToArgs(inp))
+		require.NoError(t, err)
+	}
+
+	inpTraintuple := inputTraintuple{}
+	inpTraintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inpTraintuple))
+	require.NoError(t, err)
+	traintupleToDone(t, db, traintupleKey, modelKey)
+
+	inpComposite := inputCompositeTraintuple{}
+	inpComposite.fillDefaults()
+	_, err = createCompositeTraintuple(db, assetToArgs(inpComposite))
+	require.NoError(t, err)
+	compositeToDone(t, mockStub, workerA, db, compositeTraintupleKey, RandomUUID(), RandomUUID())
+
+	childCompositeKey := RandomUUID()
+	inpComposite = inputCompositeTraintuple{
+		Key:             childCompositeKey,
+		InHeadModelKey:  compositeTraintupleKey,
+		InTrunkModelKey: traintupleKey,
+	}
+	inpComposite.fillDefaults()
+	_, err = createCompositeTraintuple(db, assetToArgs(inpComposite))
+	require.NoError(t, err)
+
+	inpAggregate := inputAggregatetuple{InModels: []string{traintupleKey}}
+	inpAggregate.fillDefaults()
+	_, err = createAggregatetuple(db, assetToArgs(inpAggregate))
+	require.NoError(t, err)
+	aggregateToDone(t, mockStub, workerA, db, aggregatetupleKey, RandomUUID())
+
+	inpTesttuple := inputTesttuple{}
+	inpTesttuple.fillDefaults()
+	clearEvent(db)
+	_, err = createTesttuple(db, assetToArgs(inpTesttuple))
+	require.NoError(t, err)
+	// the event goes to every peer of the channel, even when created by the worker
+	require.NotNil(t, db.event)
+	require.Len(t, db.event.Testtuples, 1)
+	assert.Empty(t, db.event.Testtuples[0].Algo.StorageAddress, "event testtuple algo address")
+
+	for _, node := range []string{workerA, workerB} {
+		mockStub.Creator = node
+		allowed := node == workerA
+
+		traintuple, err := queryTraintuple(db, keyToArgs(traintupleKey))
+		require.NoError(t, err)
+		assert.Equal(t, allowed, traintuple.Algo.StorageAddress != "", "traintuple algo address seen by %s", node)
+		require.NotNil(t, traintuple.OutModel)
+		assert.Equal(t, allowed, traintuple.OutModel.StorageAddress != "", "traintuple out-model address seen by %s", node)
+
+		composite, err := queryCompositeTraintuple(db, keyToArgs(childCompositeKey))
+		require.NoError(t, err)
+		assert.Equal(t, allowed, composite.Algo.StorageAddress != "", "composite algo address seen by %s", node)
+		require.NotNil(t, composite.InTrunkModel)
+		assert.Equal(t, allowed, composite.InTrunkModel.StorageAddress != "", "composite in-trunk model address seen by %s", node)
+
+		aggregate, err := queryAggregatetuple(db, keyToArgs(aggregatetupleKey))
+		require.NoError(t, err)
+		assert.Equal(t, allowed, aggregate.Algo.StorageAddress != "", "aggregate algo address seen by %s", node)
+		require.Len(t, aggregate.InModels, 1)
+		assert.Equal(t, allowed, aggregate.InModels[0].StorageAddress != "", "aggregate in-model address seen by %s", node)
+		require.NotNil(t, aggregate.OutModel)
+		assert.Equal(t, allowed, aggregate.OutModel.StorageAddress != "", "aggregate out-model address seen by %s", node)
+
+		testtuple, err := queryTesttuple(db, keyToArgs(testtupleKey))
+		require.NoError(t, err)
+		assert.Equal(t, allowed, testtuple.Algo.StorageAddress != "", "testtuple algo address seen by %s", node)
+	}
+
+	traintuple, err := db.GetTraintuple(traintupleKey)
+	require.NoError(t, err)
+	assert.NotEmpty(t, traintuple.OutModel.StorageAddress, "the stored out-model should be left untouched")
+
+	// the worker owns the out-model, whoever created the tuple
+	mockStub.Creator = workerB
+	otherTraintuple := inputTraintuple{Key: RandomUUID()}
+	otherTraintuple.createDefault()
+	_, err = createTraintuple(db, assetToArgs(otherTraintuple))
+	require.NoError(t, err)
+	mockStub.Creator = workerA
+	otherModelKey := RandomUUID()
+	traintupleToDone(t, db, otherTraintuple.Key, otherModelKey)
+	for _, node := range []string{workerA, workerB} {
+		mockStub.Creator = node
+		model, err := queryModel(db, keyToArgs(otherModelKey))
+		require.NoError(t, err)
+		out, err := queryTraintuple(db, keyToArgs(otherTraintuple.Key))
+		require.NoError(t, err)
+		require.NotNil(t, out.OutModel)
+		assert.Equal(t, model.StorageAddress, out.OutModel.StorageAddress, "out-model address seen by %s", node)
+		if node == workerA {
+			assert.NotEmpty(t, model.StorageAddress)
+		}
+	}
+}
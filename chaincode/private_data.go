@@ -0,0 +1,203 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/json"
+	"fmt"
+)
+
+// Names of the transient map entries a worker can use to keep a value off the
+// public state. When provided, the value is stored in the implicit private data
+// collection of the worker and only its hash is written to the public state.
+const (
+	transientLog                        = "log"
+	transientOutHeadModelStorageAddress = "out_head_model_storage_address"
+)
+
+// maxPrivateLogLength mirrors the validation of the public `log` argument
+const maxPrivateLogLength = 200
+
+// appendPrivateTupleLog appends the log passed in the transient map to the private
+// log of a tuple, stored in the collection of its worker. It returns the new hash of
+// the private log, or `logHash` unchanged if no private log was provided.
+func appendPrivateTupleLog(db *LedgerDB, tupleKey string, worker string, logHash string) (string, error) {
+	log, ok, err := db.GetTransient(transientLog)
+	if err != nil || !ok {
+		return logHash, err
+	}
+	if len(log) > maxPrivateLogLength {
+		return "", errors.BadRequest("private log must not exceed %d characters", maxPrivateLogLength)
+	}
+	return putPrivateTupleLogEntry(db, tupleKey, worker, logHash, string(log))
+}
+
+// putPrivateTupleLogEntry stores a log entry of a tuple in the collection of its
+// worker. Each entry is stored under its own key so that the private log is never
+// read by an invoke, which would fail on the peers outside of the collection.
+// The hash of the private log chains the hashes of its entries, which lets the
+// holders of the log check it is complete.
+func putPrivateTupleLogEntry(db *LedgerDB, tupleKey string, worker string, logHash string, log string) (string, error) {
+	txTime, err := GetTxTime(db.cc)
+	if err != nil {
+		return "", err
+	}
+	entryHash, err := db.PutPrivate(getImplicitCollection(worker), getTupleLogEntryKey(tupleKey, txTime.UnixNano()), log)
+	if err != nil {
+		return "", err
+	}
+	return chainTupleLogHash(logHash, entryHash), nil
+}
+
+// chainTupleLogHash returns the hash of a private log after an entry is appended
+func chainTupleLogHash(logHash string, entryHash string) string {
+	return hashPrivateValue([]byte(logHash + entryHash))
+}
+
+// queryTupleLog returns the private log of a tuple. It can only be served by the
+// peers of the worker organization, which hold the log.
+func queryTupleLog(db *LedgerDB, args []string) (out outputTupleLog, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	tuple, err := db.GetGenericTuple(inp.Key)
+	if err != nil {
+		return
+	}
+	worker, err := getTupleWorker(db, inp.Key)
+	if err != nil {
+		return
+	}
+	out = outputTupleLog{Key: inp.Key, Worker: worker, LogHash: tuple.LogHash}
+	if tuple.LogHash == "" {
+		return out, nil
+	}
+	prefix := getTupleLogKey(inp.Key) + "~"
+	entries, err := db.GetPrivateRange(getImplicitCollection(worker), prefix, prefix+"~")
+	if err != nil || len(entries) == 0 {
+		return out, errors.NotFound(err, "the private log of tuple %s is not available on this peer", inp.Key)
+	}
+	logHash := ""
+	for _, entry := range entries {
+		var log string
+		if err = json.Unmarshal(entry, &log); err != nil {
+			return out, errors.Internal(err, "invalid private log entry of tuple %s", inp.Key)
+		}
+		out.Log += log
+		logHash = chainTupleLogHash(logHash, hashPrivateValue(entry))
+	}
+	if logHash != tuple.LogHash {
+		return out, errors.Internal("the private log of tuple %s does not match its hash", inp.Key)
+	}
+	return out, nil
+}
+
+// getTupleWorker returns the worker of a tuple of any type
+func getTupleWorker(db *LedgerDB, tupleKey string) (string, error) {
+	assetType, err := db.GetAssetType(tupleKey)
+	if err != nil {
+		return "", err
+	}
+	switch assetType {
+	case TraintupleType, CompositeTraintupleType, AggregatetupleType:
+		return getModelOwner(db, tupleKey)
+	case TesttupleType:
+		tuple, err := db.GetTesttuple(tupleKey)
+		return tuple.Dataset.Worker, err
+	default:
+		return "", errors.BadRequest("key %s is not a tuple", tupleKey)
+	}
+}
+
+// putPrivateHeadModelStorageAddress stores the head model storage address passed in
+// the transient map in the collection of the worker. An address passed in the
+// arguments is rejected since it would be written to the transaction.
+// It returns the hash of the stored address, or an empty string if no address was
+// provided.
+func putPrivateHeadModelStorageAddress(db *LedgerDB, modelKey string, worker string, address string) (string, error) {
+	if address != "" {
+		return "", errors.BadRequest("the storage address of head model %s must be passed in the transient map, not in the arguments", modelKey)
+	}
+	transientAddress, ok, err := db.GetTransient(transientOutHeadModelStorageAddress)
+	if err != nil {
+		return "", err
+	}
+	if !ok || len(transientAddress) == 0 {
+		return "", nil
+	}
+	return db.PutPrivate(getImplicitCollection(worker), getPrivateStorageAddressKey(modelKey), string(transientAddress))
+}
+
+// sharePrivateHeadModelStorageAddress copies the head model storage address to the
+// collections of the nodes granted its download. The worker passes the address in
+// the transient map, and it is checked against its hash so that the collection of
+// the worker is not read.
+func sharePrivateHeadModelStorageAddress(db *LedgerDB, modelKey string, addressHash string, nodes []string) error {
+	if addressHash == "" {
+		return nil
+	}
+	address, ok, err := db.GetTransient(transientOutHeadModelStorageAddress)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.BadRequest("the storage address of head model %s must be passed in the transient map", modelKey)
+	}
+	buff, _ := json.Marshal(string(address))
+	if hashPrivateValue(buff) != addressHash {
+		return errors.BadRequest("the storage address passed for head model %s does not match the reported one", modelKey)
+	}
+	for _, node := range nodes {
+		if _, err := db.PutPrivate(getImplicitCollection(node), getPrivateStorageAddressKey(modelKey), string(address)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getPrivateHeadModelStorageAddress returns the head model storage address stored in
+// the collection of the worker or, on the peers of a node granted its download, in
+// the collection of the transaction creator. It returns an empty string if the peer
+// holds neither of them.
+func getPrivateHeadModelStorageAddress(db *LedgerDB, modelKey string, worker string) string {
+	collections := []string{getImplicitCollection(worker)}
+	if node, err := GetTxCreator(db.cc); err == nil && node != worker {
+		collections = append(collections, getImplicitCollection(node))
+	}
+	for _, collection := range collections {
+		address := ""
+		if err := db.GetPrivate(collection, getPrivateStorageAddressKey(modelKey), &address); err == nil {
+			return address
+		}
+	}
+	return ""
+}
+
+func getTupleLogKey(tupleKey string) string {
+	return fmt.Sprintf("tuple~%v~log", tupleKey)
+}
+
+// getTupleLogEntryKey returns the key of a private log entry, ordered by the
+// timestamp of the transaction which logged it
+func getTupleLogEntryKey(tupleKey string, timestamp int64) string {
+	return fmt.Sprintf("%v~%020d", getTupleLogKey(tupleKey), timestamp)
+}
+
+func getPrivateStorageAddressKey(modelKey string) string {
+	return fmt.Sprintf("model~%v~storageAddress", modelKey)
+}
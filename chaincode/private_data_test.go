@@ -0,0 +1,138 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivateTupleLog(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	require.NoError(t, err)
+
+	mockStub.TransientMap = map[string][]byte{transientLog: []byte(strings.Repeat("a", 201))}
+	_, err = logFailTrain(db, assetToArgs(inputLogFailTrain{inputLog{Key: traintupleKey}}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+
+	mockStub.TransientMap = map[string][]byte{transientLog: []byte("private details")}
+	failed, err := logFailTrain(db, assetToArgs(inputLogFailTrain{inputLog{Key: traintupleKey, Log: "public summary. "}}))
+	require.NoError(t, err)
+	assert.Equal(t, "public summary. ", failed.Log, "the invoke should not return the private log")
+
+	// only the hash of the private log reaches the public state
+	traintuple := Traintuple{}
+	require.NoError(t, db.Get(traintupleKey, &traintuple))
+	assert.Equal(t, "public summary. ", traintuple.Log)
+	assert.Equal(t, traintuple.LogHash, failed.LogHash)
+	assert.NotContains(t, string(mockStub.State[traintupleKey]), "private details")
+
+	out, err := queryTraintuple(db, keyToArgs(traintupleKey))
+	require.NoError(t, err)
+	assert.Equal(t, "public summary. ", out.Log)
+	assert.Equal(t, traintuple.LogHash, out.LogHash)
+
+	tupleLog, err := queryTupleLog(db, keyToArgs(traintupleKey))
+	require.NoError(t, err)
+	assert.Equal(t, outputTupleLog{Key: traintupleKey, Worker: workerA, Log: "private details", LogHash: traintuple.LogHash}, tupleLog)
+
+	// peers outside of the worker collection don't hold the private log
+	mockStub.PvtState = map[string]map[string][]byte{}
+	_, err = queryTupleLog(db, keyToArgs(traintupleKey))
+	assert.Equal(t, http.StatusNotFound, errors.Wrap(err).HTTPStatusCode())
+}
+
+func TestPrivateHeadModelStorageAddress(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "compositeTraintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := logStartCompositeTrain(db, assetToArgs(inputKey{Key: compositeTraintupleKey}))
+	require.NoError(t, err)
+	success := inputLogSuccessCompositeTrain{}
+	success.fillDefaults()
+	success.OutHeadModel.StorageAddress = headModelAddress
+	_, err = logSuccessCompositeTrain(db, assetToArgs(success))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the address should not be passed in the arguments")
+	success.OutHeadModel.StorageAddress = ""
+	mockStub.TransientMap = map[string][]byte{transientOutHeadModelStorageAddress: []byte(headModelAddress)}
+	_, err = logSuccessCompositeTrain(db, assetToArgs(success))
+	require.NoError(t, err)
+	mockStub.TransientMap = nil
+
+	tuple := CompositeTraintuple{}
+	require.NoError(t, db.Get(compositeTraintupleKey, &tuple))
+	assert.NotEmpty(t, tuple.OutHeadModel.StorageAddressHash)
+	assert.NotContains(t, string(mockStub.State[compositeTraintupleKey]), headModelAddress)
+
+	model, err := queryModel(db, keyToArgs(headModelKey))
+	require.NoError(t, err)
+	assert.Equal(t, headModelAddress, model.StorageAddress)
+	mockStub.Creator = workerB
+	model, err = queryModel(db, keyToArgs(headModelKey))
+	require.NoError(t, err)
+	assert.Empty(t, model.StorageAddress)
+}
+
+func TestMockStubPrivateDataQueries(t *testing.T) {
+	mockStub := NewMockStub("substra", new(SubstraChaincode))
+	collection := getImplicitCollection(workerA)
+	for _, key := range []string{"c", "a", "b"} {
+		require.NoError(t, mockStub.PutPrivateData(collection, key, []byte(key)))
+	}
+	require.NoError(t, mockStub.DelPrivateData(collection, "c"))
+
+	iter, err := mockStub.GetPrivateDataByRange(collection, "", "")
+	require.NoError(t, err)
+	keys := []string{}
+	for iter.HasNext() {
+		kv, err := iter.Next()
+		require.NoError(t, err)
+		assert.Equal(t, kv.Key, string(kv.Value))
+		keys = append(keys, kv.Key)
+	}
+	require.NoError(t, iter.Close())
+	assert.Equal(t, []string{"a", "b"}, keys)
+
+	hash, err := mockStub.GetPrivateDataHash(collection, "a")
+	require.NoError(t, err)
+	assert.Equal(t, hashPrivateValue([]byte("a")), hex.EncodeToString(hash))
+
+	indexKey, err := mockStub.CreateCompositeKey("tuple~key", []string{"tuple", "a"})
+	require.NoError(t, err)
+	require.NoError(t, mockStub.PutPrivateData(collection, indexKey, []byte{0x00}))
+	iter, err = mockStub.GetPrivateDataByPartialCompositeKey(collection, "tuple~key", []string{"tuple"})
+	require.NoError(t, err)
+	require.True(t, iter.HasNext())
+	kv, err := iter.Next()
+	require.NoError(t, err)
+	assert.Equal(t, indexKey, kv.Key)
+	assert.False(t, iter.HasNext())
+}
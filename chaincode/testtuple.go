@@ -50,6 +50,7 @@ func isEqual(a, b []string) bool {
 //  - Tag
 //  - Dataset
 //  - Certified
+//  - ObjectiveVersion
 func (testtuple *Testtuple) SetFromInput(db *LedgerDB, inp inputTesttuple) error {
 	creator, err := GetTxCreator(db.cc)
 	if err != nil {
@@ -67,6 +68,7 @@ func (testtuple *Testtuple) SetFromInput(db *LedgerDB, inp inputTesttuple) error
 		return errors.BadRequest(err, "could not retrieve objective with key %s", inp.ObjectiveKey)
 	}
 	testtuple.ObjectiveKey = inp.ObjectiveKey
+	testtuple.ObjectiveVersion = objective.Version
 	var objectiveDataManagerKey string
 	var objectiveDataSampleKeys []string
 	if objective.TestDataset != nil {
@@ -168,7 +170,22 @@ func (testtuple *Testtuple) SetFromTraintuple(db *LedgerDB, traintupleKey string
 		return errors.BadRequest("key %s is not a valid traintuple", traintupleKey)
 	}
 
-	if !permissions.CanProcess(tupleCreator, creator) {
+	// a composite traintuple is tested with both its head and trunk out-models
+	heads := []bool{false}
+	if traintupleType == CompositeTraintupleType {
+		heads = append(heads, true)
+	}
+	for _, head := range heads {
+		if err := checkInModelNotDeleted(db, traintupleKey, head); err != nil {
+			return err
+		}
+	}
+
+	allowed, err := permissions.CanProcess(db, tupleCreator, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process traintuple %s", traintupleKey)
 	}
 	switch status {
@@ -225,6 +242,15 @@ func (testtuple *Testtuple) Save(db *LedgerDB, testtupleKey string) error {
 	if err = db.CreateIndex("testtuple~traintuple~certified~key", []string{"testtuple", testtuple.TraintupleKey, strconv.FormatBool(testtuple.Certified), testtupleKey}); err != nil {
 		return err
 	}
+	if err = createComputePlanTupleIndex(db, testtuple.ComputePlanKey, TesttupleType, testtuple.Status, testtupleKey); err != nil {
+		return err
+	}
+	if err = createTaskIndex(db, testtuple.Dataset.Worker, testtuple.Status, testtuple.ComputePlanKey, testtuple.Rank, testtupleKey); err != nil {
+		return err
+	}
+	if err = createDataSampleUsageIndex(db, testtuple.Dataset.DataSampleKeys, testtupleKey); err != nil {
+		return err
+	}
 	if testtuple.Tag != "" {
 		err = db.CreateIndex("testtuple~tag~key", []string{"traintuple", testtuple.Tag, testtupleKey})
 		if err != nil {
@@ -302,6 +328,31 @@ func logStartTest(db *LedgerDB, args []string) (o outputTesttuple, err error) {
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	if _, err = putTupleLease(db, inp.Key, testtuple.Dataset.Worker); err != nil {
+		return
+	}
+	err = o.Fill(db, testtuple)
+	return
+}
+
+// logProgressTest reports the progress of a testtuple in the doing state
+func logProgressTest(db *LedgerDB, args []string) (o outputTesttuple, err error) {
+	inp := inputLogProgress{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+
+	testtuple, err := db.GetTesttuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
+		return
+	}
+	if err = putTupleProgress(db, inp, testtuple.Status); err != nil {
+		return
+	}
 	err = o.Fill(db, testtuple)
 	return
 }
@@ -326,6 +377,10 @@ func logSuccessTest(db *LedgerDB, args []string) (o outputTesttuple, err error)
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	testtuple.LogHash, err = appendPrivateTupleLog(db, inp.Key, testtuple.Dataset.Worker, testtuple.LogHash)
+	if err != nil {
+		return
+	}
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -353,6 +408,10 @@ func logFailTest(db *LedgerDB, args []string) (o outputTesttuple, err error) {
 	if err = validateTupleOwner(db, testtuple.Dataset.Worker); err != nil {
 		return
 	}
+	testtuple.LogHash, err = appendPrivateTupleLog(db, inp.Key, testtuple.Dataset.Worker, testtuple.LogHash)
+	if err != nil {
+		return
+	}
 	if err = testtuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -441,18 +500,24 @@ func getOutputTesttuples(db *LedgerDB, testtupleKeys []string) (outTesttuples []
 }
 
 // validateNewStatus verifies that the new status is consistent with the tuple current status
-func (testtuple *Testtuple) validateNewStatus(db *LedgerDB, status string) error {
+func (testtuple *Testtuple) validateNewStatus(db *LedgerDB, status string, requeue bool) error {
 	// check validity of worker and change of status
-	return checkUpdateTuple(db, testtuple.Dataset.Worker, testtuple.Status, status)
+	return checkUpdateTuple(db, testtuple.Dataset.Worker, testtuple.Status, status, requeue)
 }
 
 // commitStatusUpdate update the testtuple status in the ledger
 func (testtuple *Testtuple) commitStatusUpdate(db *LedgerDB, testtupleKey string, newStatus string) error {
+	return testtuple.updateStatus(db, testtupleKey, newStatus, false)
+}
+
+// updateStatus updates the testtuple status in the ledger. Setting requeue allows
+// to send a "doing" tuple back to "todo", which only the lease reaper does.
+func (testtuple *Testtuple) updateStatus(db *LedgerDB, testtupleKey string, newStatus string, requeue bool) error {
 	if testtuple.Status == newStatus {
 		return nil
 	}
 
-	if err := testtuple.validateNewStatus(db, newStatus); err != nil {
+	if err := testtuple.validateNewStatus(db, newStatus, requeue); err != nil {
 		return errors.Internal("update testtuple %s failed: %s", testtupleKey, err.Error())
 	}
 
@@ -475,6 +540,12 @@ func (testtuple *Testtuple) commitStatusUpdate(db *LedgerDB, testtupleKey string
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := updateComputePlanTupleIndex(db, testtuple.ComputePlanKey, TesttupleType, oldStatus, newStatus, testtupleKey); err != nil {
+		return err
+	}
+	if err := updateTaskIndex(db, testtuple.Dataset.Worker, oldStatus, newStatus, testtuple.ComputePlanKey, testtuple.Rank, testtupleKey); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, testtuple.ComputePlanKey, newStatus, testtupleKey, testtuple.Dataset.Worker); err != nil {
 		return err
 	}
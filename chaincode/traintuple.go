@@ -45,11 +45,16 @@ func (traintuple *Traintuple) SetFromInput(db *LedgerDB, inp inputTraintuple) er
 	traintuple.ComputePlanKey = inp.ComputePlanKey
 	traintuple.Metadata = inp.Metadata
 	traintuple.Tag = inp.Tag
+	traintuple.KeepModel = inp.KeepModel
 	algo, err := db.GetAlgo(inp.AlgoKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
 	}
-	if !algo.Permissions.CanProcess(algo.Owner, creator) {
+	allowed, err := algo.Permissions.CanProcess(db, algo.Owner, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
 	traintuple.AlgoKey = inp.AlgoKey
@@ -67,11 +72,18 @@ func (traintuple *Traintuple) SetFromInput(db *LedgerDB, inp inputTraintuple) er
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve dataManager with key \"%s\"", inp.DataManagerKey)
 	}
-	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
+	allowed, err = dataManager.Permissions.CanProcess(db, dataManager.Owner, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process dataManager %s", inp.DataManagerKey)
 	}
 
-	traintuple.Permissions = MergePermissions(dataManager.Permissions, algo.Permissions)
+	traintuple.Permissions, err = MergePermissions(db, dataManager.Permissions, algo.Permissions)
+	if err != nil {
+		return err
+	}
 
 	// fill traintuple.Dataset from dataManager and dataSample
 	traintuple.Dataset = &Dataset{
@@ -94,12 +106,19 @@ func (traintuple *Traintuple) SetFromParents(db *LedgerDB, inModels []string) er
 		if err != nil {
 			return errors.BadRequest(err, "could not retrieve parent traintuple with key %s", parentTraintupleKey)
 		}
-		if !typeInSlice(tuple.AssetType, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType}) {
+		if !typeInSlice(tuple.AssetType, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType}) {
 			return errors.Internal("aggregate.SetFromParents: Unsupported parent type %s", tuple.AssetType)
 		}
+		if err := checkInModelNotDeleted(db, parentTraintupleKey, false); err != nil {
+			return err
+		}
 		parentStatuses = append(parentStatuses, tuple.Status)
 		inModelKeys = append(inModelKeys, parentTraintupleKey)
 	}
+	// the worker must be allowed to process the out-model of every parent
+	if err := checkInModelsPermissions(db, traintuple.Dataset.Worker, inModels, false); err != nil {
+		return err
+	}
 	traintuple.Status = determineStatusFromInModels(parentStatuses)
 	traintuple.InModelKeys = inModelKeys
 	return nil
@@ -181,6 +200,15 @@ func (traintuple *Traintuple) Save(db *LedgerDB, traintupleKey string) error {
 			return err
 		}
 	}
+	if err := createComputePlanTupleIndex(db, traintuple.ComputePlanKey, TraintupleType, traintuple.Status, traintupleKey); err != nil {
+		return err
+	}
+	if err := createTaskIndex(db, traintuple.Dataset.Worker, traintuple.Status, traintuple.ComputePlanKey, traintuple.Rank, traintupleKey); err != nil {
+		return err
+	}
+	if err := createDataSampleUsageIndex(db, traintuple.Dataset.DataSampleKeys, traintupleKey); err != nil {
+		return err
+	}
 	if traintuple.Tag != "" {
 		err := db.CreateIndex("traintuple~tag~key", []string{"traintuple", traintuple.Tag, traintupleKey})
 		if err != nil {
@@ -268,6 +296,31 @@ func logStartTrain(db *LedgerDB, args []string) (o outputTraintuple, err error)
 	if err = traintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	if _, err = putTupleLease(db, inp.Key, traintuple.Dataset.Worker); err != nil {
+		return
+	}
+	err = o.Fill(db, traintuple)
+	return
+}
+
+// logProgressTrain reports the progress of a traintuple in the doing state
+func logProgressTrain(db *LedgerDB, args []string) (o outputTraintuple, err error) {
+	inp := inputLogProgress{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+
+	traintuple, err := db.GetTraintuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+		return
+	}
+	if err = putTupleProgress(db, inp, traintuple.Status); err != nil {
+		return
+	}
 	err = o.Fill(db, traintuple)
 	return
 }
@@ -289,6 +342,17 @@ func logSuccessTrain(db *LedgerDB, args []string) (o outputTraintuple, err error
 		return
 	}
 
+	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+		return
+	}
+	if err = checkOutModel(db, traintupleKey, inp.OutModel.Key, inp.OutModel.Checksum, inp.AllowChecksumReuse); err != nil {
+		return
+	}
+	traintuple.LogHash, err = appendPrivateTupleLog(db, traintupleKey, traintuple.Dataset.Worker, traintuple.LogHash)
+	if err != nil {
+		return
+	}
+
 	traintuple.OutModel = &KeyChecksumAddress{
 		Key:            inp.OutModel.Key,
 		Checksum:       inp.OutModel.Checksum,
@@ -299,8 +363,8 @@ func logSuccessTrain(db *LedgerDB, args []string) (o outputTraintuple, err error
 	if err != nil {
 		return
 	}
-
-	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
+	err = createModelChecksumIndex(db, inp.OutModel.Checksum, traintupleKey)
+	if err != nil {
 		return
 	}
 
@@ -348,6 +412,10 @@ func logFailTrain(db *LedgerDB, args []string) (o outputTraintuple, err error) {
 	if err = validateTupleOwner(db, traintuple.Dataset.Worker); err != nil {
 		return
 	}
+	traintuple.LogHash, err = appendPrivateTupleLog(db, inp.Key, traintuple.Dataset.Worker, traintuple.LogHash)
+	if err != nil {
+		return
+	}
 	if err = traintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -451,9 +519,9 @@ func getOutputTraintuples(db *LedgerDB, traintupleKeys []string) (outTraintuples
 }
 
 // validateNewStatus verifies that the new status is consistent with the tuple current status
-func (traintuple *Traintuple) validateNewStatus(db *LedgerDB, status string) error {
+func (traintuple *Traintuple) validateNewStatus(db *LedgerDB, status string, requeue bool) error {
 	// check validity of worker and change of status
-	return checkUpdateTuple(db, traintuple.Dataset.Worker, traintuple.Status, status)
+	return checkUpdateTuple(db, traintuple.Dataset.Worker, traintuple.Status, status, requeue)
 }
 
 // UpdateTraintupleChildren updates the status of waiting trainuples  InModels of traintuples once they have been trained (succesfully or failed)
@@ -586,6 +654,12 @@ func IsReady(db *LedgerDB, inModelKeys []string, newDoneTraintupleKey string) (r
 
 // commitStatusUpdate update the traintuple status in the ledger
 func (traintuple *Traintuple) commitStatusUpdate(db *LedgerDB, traintupleKey string, newStatus string) error {
+	return traintuple.updateStatus(db, traintupleKey, newStatus, false)
+}
+
+// updateStatus updates the traintuple status in the ledger. Setting requeue allows
+// to send a "doing" tuple back to "todo", which only the lease reaper does.
+func (traintuple *Traintuple) updateStatus(db *LedgerDB, traintupleKey string, newStatus string, requeue bool) error {
 	if traintuple.Status == newStatus {
 		return nil
 	}
@@ -595,7 +669,7 @@ func (traintuple *Traintuple) commitStatusUpdate(db *LedgerDB, traintupleKey str
 		return nil
 	}
 
-	if err := traintuple.validateNewStatus(db, newStatus); err != nil {
+	if err := traintuple.validateNewStatus(db, newStatus, requeue); err != nil {
 		return errors.Internal("update traintuple %s failed: %s", traintupleKey, err.Error())
 	}
 
@@ -612,6 +686,12 @@ func (traintuple *Traintuple) commitStatusUpdate(db *LedgerDB, traintupleKey str
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := updateComputePlanTupleIndex(db, traintuple.ComputePlanKey, TraintupleType, oldStatus, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := updateTaskIndex(db, traintuple.Dataset.Worker, oldStatus, newStatus, traintuple.ComputePlanKey, traintuple.Rank, traintupleKey); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, traintuple.ComputePlanKey, newStatus, traintupleKey, traintuple.Dataset.Worker); err != nil {
 		return err
 	}
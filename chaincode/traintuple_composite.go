@@ -43,11 +43,16 @@ func (traintuple *CompositeTraintuple) SetFromInput(db *LedgerDB, inp inputCompo
 	traintuple.ComputePlanKey = inp.ComputePlanKey
 	traintuple.Metadata = inp.Metadata
 	traintuple.Tag = inp.Tag
+	traintuple.KeepModel = inp.KeepModel
 	algo, err := db.GetCompositeAlgo(inp.AlgoKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve Composite algo with key %s", inp.AlgoKey)
 	}
-	if !algo.Permissions.CanProcess(algo.Owner, creator) {
+	allowed, err := algo.Permissions.CanProcess(db, algo.Owner, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
 	traintuple.AlgoKey = inp.AlgoKey
@@ -65,7 +70,11 @@ func (traintuple *CompositeTraintuple) SetFromInput(db *LedgerDB, inp inputCompo
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve dataManager with key %s", inp.DataManagerKey)
 	}
-	if !dataManager.Permissions.CanProcess(dataManager.Owner, creator) {
+	allowed, err = dataManager.Permissions.CanProcess(db, dataManager.Owner, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process dataManager %s", inp.DataManagerKey)
 	}
 
@@ -131,17 +140,44 @@ func (traintuple *CompositeTraintuple) SetFromParents(db *LedgerDB, inp inputCom
 	// - a traintuple's out model
 	// - a composite traintuple's trunk out model
 	// - an aggregate tuple's out model
+	// - a registered external model
 	traintuple.InTrunkModel = inp.InTrunkModelKey
 	trunk, err := db.GetGenericTuple(inp.InTrunkModelKey)
 	if err != nil {
 		return err
 	}
-	if !typeInSlice(trunk.AssetType, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType}) {
+	if !typeInSlice(trunk.AssetType, []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, ExternalModelType}) {
 		return errors.BadRequest(
 			"tuple type %s from key %s is not supported as trunk InModel",
 			trunk.AssetType,
 			inp.InTrunkModelKey)
 	}
+	// both in-models must still exist
+	if err := checkInModelNotDeleted(db, inp.InHeadModelKey, true); err != nil {
+		return err
+	}
+	if err := checkInModelNotDeleted(db, inp.InTrunkModelKey, false); err != nil {
+		return err
+	}
+	// the worker must be allowed to process both in-models
+	forbiddenKeys := []string{}
+	headAllowed, err := canProcessInModel(db, traintuple.Dataset.Worker, inp.InHeadModelKey, true)
+	if err != nil {
+		return err
+	}
+	if !headAllowed {
+		forbiddenKeys = append(forbiddenKeys, inp.InHeadModelKey)
+	}
+	trunkAllowed, err := canProcessInModel(db, traintuple.Dataset.Worker, inp.InTrunkModelKey, false)
+	if err != nil {
+		return err
+	}
+	if !trunkAllowed {
+		forbiddenKeys = append(forbiddenKeys, inp.InTrunkModelKey)
+	}
+	if err = forbiddenInModelsError(traintuple.Dataset.Worker, forbiddenKeys); err != nil {
+		return err
+	}
 	traintuple.Status = determineStatusFromInModels([]string{head.Status, trunk.Status})
 	return nil
 }
@@ -225,6 +261,15 @@ func (traintuple *CompositeTraintuple) Save(db *LedgerDB, traintupleKey string)
 			return err
 		}
 	}
+	if err := createComputePlanTupleIndex(db, traintuple.ComputePlanKey, CompositeTraintupleType, traintuple.Status, traintupleKey); err != nil {
+		return err
+	}
+	if err := createTaskIndex(db, traintuple.Dataset.Worker, traintuple.Status, traintuple.ComputePlanKey, traintuple.Rank, traintupleKey); err != nil {
+		return err
+	}
+	if err := createDataSampleUsageIndex(db, traintuple.Dataset.DataSampleKeys, traintupleKey); err != nil {
+		return err
+	}
 	if traintuple.Tag != "" {
 		err := db.CreateIndex("compositeTraintuple~tag~key", []string{"compositeTraintuple", traintuple.Tag, traintupleKey})
 		if err != nil {
@@ -312,6 +357,31 @@ func logStartCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTrain
 	if err = compositeTraintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	if _, err = putTupleLease(db, inp.Key, compositeTraintuple.Dataset.Worker); err != nil {
+		return
+	}
+	err = o.Fill(db, compositeTraintuple)
+	return
+}
+
+// logProgressCompositeTrain reports the progress of a composite traintuple in the doing state
+func logProgressCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTraintuple, err error) {
+	inp := inputLogProgress{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+
+	compositeTraintuple, err := db.GetCompositeTraintuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
+		return
+	}
+	if err = putTupleProgress(db, inp, compositeTraintuple.Status); err != nil {
+		return
+	}
 	err = o.Fill(db, compositeTraintuple)
 	return
 }
@@ -333,6 +403,28 @@ func logSuccessCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTra
 		return
 	}
 
+	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
+		return
+	}
+	if inp.OutHeadModel.Key == inp.OutTrunkModel.Key {
+		err = errors.BadRequest("head and trunk out-models must have different keys")
+		return
+	}
+	if err = checkOutModel(db, compositeTraintupleKey, inp.OutHeadModel.Key, inp.OutHeadModel.Checksum, inp.AllowChecksumReuse); err != nil {
+		return
+	}
+	if err = checkOutModel(db, compositeTraintupleKey, inp.OutTrunkModel.Key, inp.OutTrunkModel.Checksum, inp.AllowChecksumReuse); err != nil {
+		return
+	}
+	compositeTraintuple.LogHash, err = appendPrivateTupleLog(db, compositeTraintupleKey, compositeTraintuple.Dataset.Worker, compositeTraintuple.LogHash)
+	if err != nil {
+		return
+	}
+	compositeTraintuple.OutHeadModel.StorageAddressHash, err = putPrivateHeadModelStorageAddress(db, inp.OutHeadModel.Key, compositeTraintuple.Dataset.Worker, inp.OutHeadModel.StorageAddress)
+	if err != nil {
+		return
+	}
+
 	compositeTraintuple.OutHeadModel.OutModel = &KeyChecksum{
 		Key:      inp.OutHeadModel.Key,
 		Checksum: inp.OutHeadModel.Checksum}
@@ -351,8 +443,12 @@ func logSuccessCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTra
 	if err != nil {
 		return
 	}
-
-	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
+	err = createModelChecksumIndex(db, inp.OutHeadModel.Checksum, compositeTraintupleKey)
+	if err != nil {
+		return
+	}
+	err = createModelChecksumIndex(db, inp.OutTrunkModel.Checksum, compositeTraintupleKey)
+	if err != nil {
 		return
 	}
 
@@ -403,6 +499,10 @@ func logFailCompositeTrain(db *LedgerDB, args []string) (o outputCompositeTraint
 	if err = validateTupleOwner(db, compositeTraintuple.Dataset.Worker); err != nil {
 		return
 	}
+	compositeTraintuple.LogHash, err = appendPrivateTupleLog(db, inp.Key, compositeTraintuple.Dataset.Worker, compositeTraintuple.LogHash)
+	if err != nil {
+		return
+	}
 	if err = compositeTraintuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -477,6 +577,54 @@ func queryCompositeTraintuples(db *LedgerDB, args []string) (outTraintuples []ou
 	return
 }
 
+// grantHeadModelDownload allows nodes to download the head out-model of a
+// composite traintuple. Only the worker holding the head model can grant it.
+func grantHeadModelDownload(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputGrantHeadModelDownload{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	keys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", inp.Key})
+	if err != nil {
+		return
+	}
+	if len(keys) == 0 {
+		return resp, errors.NotFound("Could not find a model for key %s", inp.Key)
+	}
+	// read the stored tuple as is, since the getter may adjust its status
+	traintuple := CompositeTraintuple{}
+	if err = db.Get(keys[0], &traintuple); err != nil {
+		return
+	}
+	if traintuple.AssetType != CompositeTraintupleType || traintuple.OutHeadModel.OutModel == nil || traintuple.OutHeadModel.OutModel.Key != inp.Key {
+		return resp, errors.BadRequest("model %s is not a head model", inp.Key)
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != traintuple.Dataset.Worker {
+		return resp, errors.Forbidden("%s is not allowed to grant the download of head model %s", txCreator, inp.Key)
+	}
+	if err = validateAuthorizedIds(db, inp.AuthorizedIDs); err != nil {
+		return
+	}
+	if err = sharePrivateHeadModelStorageAddress(db, inp.Key, traintuple.OutHeadModel.StorageAddressHash, inp.AuthorizedIDs); err != nil {
+		return
+	}
+	download := &traintuple.OutHeadModel.Permissions.Download
+	for _, authorizedID := range inp.AuthorizedIDs {
+		if !stringInSlice(authorizedID, download.AuthorizedIDs) {
+			download.AuthorizedIDs = append(download.AuthorizedIDs, authorizedID)
+		}
+	}
+	if err = db.Put(keys[0], traintuple); err != nil {
+		return
+	}
+	return outputKey{Key: inp.Key}, nil
+}
+
 // ----------------------------------------------------------
 // Utils for smartcontracts related to composite traintuples
 // ----------------------------------------------------------
@@ -545,9 +693,9 @@ func getOutputCompositeTraintuples(db *LedgerDB, traintupleKeys []string) (outTr
 }
 
 // validateNewStatus verifies that the new status is consistent with the tuple current status
-func (traintuple *CompositeTraintuple) validateNewStatus(db *LedgerDB, status string) error {
+func (traintuple *CompositeTraintuple) validateNewStatus(db *LedgerDB, status string, requeue bool) error {
 	// check validity of worker and change of status
-	if err := checkUpdateTuple(db, traintuple.Dataset.Worker, traintuple.Status, status); err != nil {
+	if err := checkUpdateTuple(db, traintuple.Dataset.Worker, traintuple.Status, status, requeue); err != nil {
 		return err
 	}
 	return nil
@@ -559,6 +707,12 @@ func (traintuple *CompositeTraintuple) isReady(db *LedgerDB, newDoneTraintupleKe
 
 // commitStatusUpdate update the traintuple status in the ledger
 func (traintuple *CompositeTraintuple) commitStatusUpdate(db *LedgerDB, traintupleKey string, newStatus string) error {
+	return traintuple.updateStatus(db, traintupleKey, newStatus, false)
+}
+
+// updateStatus updates the composite traintuple status in the ledger. Setting requeue allows
+// to send a "doing" tuple back to "todo", which only the lease reaper does.
+func (traintuple *CompositeTraintuple) updateStatus(db *LedgerDB, traintupleKey string, newStatus string, requeue bool) error {
 	if traintuple.Status == newStatus {
 		return nil
 	}
@@ -568,7 +722,7 @@ func (traintuple *CompositeTraintuple) commitStatusUpdate(db *LedgerDB, traintup
 		return nil
 	}
 
-	if err := traintuple.validateNewStatus(db, newStatus); err != nil {
+	if err := traintuple.validateNewStatus(db, newStatus, requeue); err != nil {
 		return errors.Internal("update traintuple %s failed: %s", traintupleKey, err.Error())
 	}
 
@@ -585,6 +739,12 @@ func (traintuple *CompositeTraintuple) commitStatusUpdate(db *LedgerDB, traintup
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := updateComputePlanTupleIndex(db, traintuple.ComputePlanKey, CompositeTraintupleType, oldStatus, newStatus, traintupleKey); err != nil {
+		return err
+	}
+	if err := updateTaskIndex(db, traintuple.Dataset.Worker, oldStatus, newStatus, traintuple.ComputePlanKey, traintuple.Rank, traintupleKey); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, traintuple.ComputePlanKey, newStatus, traintupleKey, traintuple.Dataset.Worker); err != nil {
 		return err
 	}
@@ -860,3 +860,37 @@ func TestHeadModelDifferentWorker(t *testing.T) {
 	assert.IsType(t, errors.BadRequest(), err)
 	assert.Zero(t, out.Key)
 }
+
+func TestCompositeTraintupleInModelPermissions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+
+	// trunk restricted to its owner
+	parentA := inputCompositeTraintuple{Key: RandomUUID()}
+	parentA.fillDefaults()
+	parentA.OutTrunkModelPermissions = inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}}
+	resp := mockStub.MockInvoke(parentA.getArgs())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	parentB := inputCompositeTraintuple{Key: RandomUUID(), DataManagerKey: dataManagerKey2, DataSampleKeys: []string{trainDataSampleKeyWorker2}}
+	parentB.fillDefaults()
+	resp = mockStub.MockInvoke(parentB.getArgs())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	child := inputCompositeTraintuple{
+		Key:             RandomUUID(),
+		DataManagerKey:  dataManagerKey2,
+		DataSampleKeys:  []string{trainDataSampleKeyWorker2},
+		InHeadModelKey:  parentB.Key,
+		InTrunkModelKey: parentA.Key,
+	}
+	child.fillDefaults()
+	resp = mockStub.MockInvoke(child.getArgs())
+	assert.EqualValues(t, 403, resp.Status, "the worker should be able to process the trunk in-model")
+	assert.Contains(t, resp.Message, parentA.Key)
+	assert.NotContains(t, resp.Message, parentB.Key)
+
+	child.InTrunkModelKey = parentB.Key
+	resp = mockStub.MockInvoke(child.getArgs())
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+}
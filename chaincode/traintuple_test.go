@@ -15,6 +15,7 @@
 package main
 
 import (
+	"chaincode/errors"
 	"encoding/json"
 	"net/http"
 	"strings"
@@ -508,3 +509,81 @@ func TestInsertTraintupleTwice(t *testing.T) {
 	assert.EqualValues(t, http.StatusConflict, resp.Status)
 
 }
+
+func TestLogSuccessTrainOutModelChecks(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+	traintupleToDone(t, db, out.TraintupleKeys[0], modelKey)
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: out.TraintupleKeys[1]}))
+	require.NoError(t, err)
+
+	success := inputLogSuccessTrain{}
+	success.Key = out.TraintupleKeys[1]
+	success.OutModel.Key = RandomUUID()
+	success.OutModel.Checksum = GetRandomHash()
+	success.fillDefaults()
+
+	// the owner check happens before any index is written
+	mockStub.Creator = workerB
+	_, err = logSuccessTrain(db, assetToArgs(success))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+	keys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", success.OutModel.Key})
+	require.NoError(t, err)
+	assert.Empty(t, keys)
+	mockStub.Creator = workerA
+
+	// an out-model key can only be bound to one tuple
+	reusedKey := success
+	reusedKey.OutModel.Key = modelKey
+	_, err = logSuccessTrain(db, assetToArgs(reusedKey))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+
+	// a checksum can only be reported by one tuple, unless explicitly allowed
+	parent, err := db.GetTraintuple(out.TraintupleKeys[0])
+	require.NoError(t, err)
+	reusedChecksum := success
+	reusedChecksum.OutModel.Checksum = parent.OutModel.Checksum
+	_, err = logSuccessTrain(db, assetToArgs(reusedChecksum))
+	assert.Equal(t, http.StatusConflict, errors.Wrap(err).HTTPStatusCode())
+	reusedChecksum.AllowChecksumReuse = true
+	_, err = logSuccessTrain(db, assetToArgs(reusedChecksum))
+	assert.NoError(t, err)
+}
+
+func TestTraintupleInModelPermissions(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	// restrict the algo, and so the out-model, to its owner
+	_, err := updatePermissions(db, assetToArgs(inputUpdatePermissions{
+		Key:         algoKey,
+		Permissions: inputPermissions{Process: inputPermission{Public: false, AuthorizedIDs: []string{}}},
+	}))
+	require.NoError(t, err)
+
+	parent := inputTraintuple{Key: RandomUUID()}
+	resp := mockStub.MockInvoke(parent.createDefault())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	child := inputTraintuple{
+		Key:            RandomUUID(),
+		InModels:       []string{parent.Key},
+		DataManagerKey: dataManagerKey2,
+		DataSampleKeys: []string{trainDataSampleKeyWorker2},
+	}
+	resp = mockStub.MockInvoke(child.createDefault())
+	assert.EqualValues(t, 403, resp.Status, "the worker should be able to process the in-models")
+	assert.Contains(t, resp.Message, parent.Key)
+
+	child = inputTraintuple{Key: RandomUUID(), InModels: []string{parent.Key}}
+	resp = mockStub.MockInvoke(child.createDefault())
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+}
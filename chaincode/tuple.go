@@ -17,6 +17,7 @@ package main
 import (
 	"chaincode/errors"
 	"encoding/json"
+	"fmt"
 )
 
 // List of the possible tuple's status
@@ -73,6 +74,12 @@ func queryModelDetails(db *LedgerDB, args []string) (outModelDetails outputModel
 		outModelDetails.Aggregatetuple = &out
 	}
 
+	// get the deletion records of the tuple out-models
+	outModelDetails.DeletedModels, err = getTupleModelDeletions(db, inp.Key)
+	if err != nil {
+		return
+	}
+
 	// get certified and non-certified testtuples related to traintuple
 	testtupleKeys, err := db.GetIndexKeys("testtuple~traintuple~certified~key", []string{"testtuple", inp.Key})
 	if err != nil {
@@ -95,20 +102,172 @@ func queryModelDetails(db *LedgerDB, args []string) (outModelDetails outputModel
 	return
 }
 
+// updateKeepModel pins or unpins the out-models of a tuple. Pinned models are
+// never scheduled for deletion by compute plans cleaning their intermediary models.
+// Only the tuple creator can update it.
+func updateKeepModel(db *LedgerDB, args []string) (resp outputKey, err error) {
+	inp := inputUpdateKeepModel{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	tuple, err := db.GetGenericTuple(inp.Key)
+	if err != nil {
+		return
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	if txCreator != tuple.Creator {
+		return resp, errors.Forbidden("%s is not allowed to update tuple %s", txCreator, inp.Key)
+	}
+
+	// read the stored tuples as is, since getters may adjust their status
+	var asset interface{}
+	switch tuple.AssetType {
+	case TraintupleType:
+		traintuple := Traintuple{}
+		if err = db.Get(inp.Key, &traintuple); err != nil {
+			return
+		}
+		traintuple.KeepModel = inp.KeepModel
+		asset = traintuple
+	case CompositeTraintupleType:
+		compositeTraintuple := CompositeTraintuple{}
+		if err = db.Get(inp.Key, &compositeTraintuple); err != nil {
+			return
+		}
+		compositeTraintuple.KeepModel = inp.KeepModel
+		asset = compositeTraintuple
+	case AggregatetupleType:
+		aggregatetuple := Aggregatetuple{}
+		if err = db.Get(inp.Key, &aggregatetuple); err != nil {
+			return
+		}
+		aggregatetuple.KeepModel = inp.KeepModel
+		asset = aggregatetuple
+	default:
+		return resp, errors.BadRequest("key %s is not a tuple producing a model", inp.Key)
+	}
+	if err = db.Put(inp.Key, asset); err != nil {
+		return
+	}
+	return outputKey{Key: inp.Key}, nil
+}
+
+// queryModelsBookmarks is the bookmark of queryModels, serialized as JSON.
+// The models are listed by merging the "<type>~algo~key" indexes of the tuple
+// types producing models, so that they are ordered by algo key then tuple key
+// whatever their type. The bookmark holds the position reached in each index:
+//
+//	{
+//	  "traintuple": {"bookmark": "...", "offset": 3, "done": false},
+//	  "composite_traintuple": {"bookmark": "...", "offset": 0, "done": false},
+//	  "aggregatetuple": {"bookmark": "", "offset": 1, "done": true}
+//	}
+//
+// where "bookmark" is the index bookmark of the page being read, "offset" the
+// number of keys of that page already listed and "done" is set once the index
+// is exhausted. An empty bookmark is returned once all the models are listed.
+// A call scans at most queryModelsScanLimit tuples, so that a selective filter
+// may return a short or even empty page along with a bookmark to carry on.
 type queryModelsBookmarks struct {
-	Traintuple          string `json:"traintuple"`
-	CompositeTraintuple string `json:"composite_traintuple"`
-	Aggregatetuple      string `json:"aggregatetuple"`
+	Traintuple          queryModelsCursor `json:"traintuple"`
+	CompositeTraintuple queryModelsCursor `json:"composite_traintuple"`
+	Aggregatetuple      queryModelsCursor `json:"aggregatetuple"`
 }
 
-type inputQueryModelsBookmarks struct {
+// queryModelsCursor is the position reached by queryModels in an index
+type queryModelsCursor struct {
 	Bookmark string `json:"bookmark"`
+	Offset   int    `json:"offset"`
+	Done     bool   `json:"done"`
+}
+
+// queryModelsScanLimit is the maximum number of tuples read by a queryModels call
+var queryModelsScanLimit = 4 * OutputPageSize
+
+// modelIndexReader reads the keys of a tuple index one at a time, starting
+// from a cursor which it keeps up to date
+type modelIndexReader struct {
+	index        string
+	attributes   []string
+	cursor       *queryModelsCursor
+	loaded       bool
+	keys         []string
+	nextBookmark string
+	tuple        *GenericTuple
+}
+
+// peek returns the key and the tuple at the cursor position, or an empty key
+// once the index is exhausted
+func (reader *modelIndexReader) peek(db *LedgerDB) (string, GenericTuple, error) {
+	for !reader.cursor.Done {
+		if !reader.loaded {
+			keys, nextBookmark, err := db.GetIndexKeysWithPagination(reader.index, reader.attributes, OutputPageSize, reader.cursor.Bookmark)
+			if err != nil {
+				return "", GenericTuple{}, err
+			}
+			reader.keys = keys
+			reader.nextBookmark = nextBookmark
+			reader.loaded = true
+		}
+		if reader.cursor.Offset < len(reader.keys) {
+			key := reader.keys[reader.cursor.Offset]
+			if reader.tuple == nil {
+				tuple, err := db.GetGenericTuple(key)
+				if err != nil {
+					return "", GenericTuple{}, err
+				}
+				reader.tuple = &tuple
+			}
+			return key, *reader.tuple, nil
+		}
+		if reader.nextBookmark == "" || len(reader.keys) < OutputPageSize {
+			reader.cursor.Done = true
+		} else {
+			reader.cursor.Bookmark = reader.nextBookmark
+			reader.cursor.Offset = 0
+			reader.loaded = false
+		}
+	}
+	return "", GenericTuple{}, nil
+}
+
+// next moves the cursor past the current key
+func (reader *modelIndexReader) next() {
+	reader.cursor.Offset++
+	reader.tuple = nil
 }
 
-// queryModels returns all traintuples and associated testuples
+// match checks if a tuple matches the filters of the query
+func (inp inputQueryModels) match(db *LedgerDB, key string, tuple GenericTuple) (bool, error) {
+	if inp.ComputePlanKey != "" && tuple.ComputePlanKey != inp.ComputePlanKey {
+		return false, nil
+	}
+	if inp.Status != "" && tuple.Status != inp.Status {
+		return false, nil
+	}
+	if inp.Tag != "" && tuple.Tag != inp.Tag {
+		return false, nil
+	}
+	if inp.Worker == "" {
+		return true, nil
+	}
+	worker, err := getModelOwner(db, key)
+	if err != nil {
+		return false, err
+	}
+	return worker == inp.Worker, nil
+}
+
+// queryModels returns the traintuples, composite traintuples and aggregate
+// tuples, optionally filtered by compute plan, algo, worker, status and tag.
+// See queryModelsBookmarks for the order of the results and the bookmark format.
 func queryModels(db *LedgerDB, args []string) (outModels []outputModelListItem, bookmark string, err error) {
+	inp := inputQueryModels{}
 	outModels = []outputModelListItem{}
-	bookmarks := queryModelsBookmarks{}
 
 	if len(args) > 1 {
 		err = errors.BadRequest("incorrect number of arguments, expecting at most one argument")
@@ -116,79 +275,93 @@ func queryModels(db *LedgerDB, args []string) (outModels []outputModelListItem,
 	}
 
 	if len(args) == 1 && args[0] != "" {
-		inp := inputQueryModelsBookmarks{}
-		err = json.Unmarshal([]byte(args[0]), &inp)
+		err = AssetFromJSON(args, &inp)
 		if err != nil {
 			return
 		}
-		if inp.Bookmark != "" {
-			err = json.Unmarshal([]byte(inp.Bookmark), &bookmarks)
-			if err != nil {
-				return
-			}
-		}
-	}
-
-	// populate from regular traintuples
-	traintupleKeys, traintupleBookmark, err := db.GetIndexKeysWithPagination("traintuple~algo~key", []string{"traintuple"}, OutputPageSize/3, bookmarks.Traintuple)
-	bookmarks.Traintuple = traintupleBookmark
-
-	if err != nil {
-		return
 	}
 
-	for _, traintupleKey := range traintupleKeys {
-		var outputModel outputModelListItem
-		var out outputTraintuple
-
-		out, err = getOutputTraintuple(db, traintupleKey)
+	bookmarks := queryModelsBookmarks{}
+	if inp.Bookmark != "" {
+		err = json.Unmarshal([]byte(inp.Bookmark), &bookmarks)
 		if err != nil {
-			return
+			return outModels, "", errors.BadRequest(err, "invalid bookmark")
 		}
-		outputModel.Traintuple = &out
-		outModels = append(outModels, outputModel)
 	}
 
-	// populate from composite traintuples
-	compositeTraintupleKeys, compositeTraintupleBookmark, err := db.GetIndexKeysWithPagination("compositeTraintuple~algo~key", []string{"compositeTraintuple"}, OutputPageSize/3, bookmarks.CompositeTraintuple)
-	bookmarks.CompositeTraintuple = compositeTraintupleBookmark
-
-	if err != nil {
-		return
+	readers := []*modelIndexReader{
+		{index: "traintuple~algo~key", attributes: []string{"traintuple"}, cursor: &bookmarks.Traintuple},
+		{index: "compositeTraintuple~algo~key", attributes: []string{"compositeTraintuple"}, cursor: &bookmarks.CompositeTraintuple},
+		{index: "aggregatetuple~algo~key", attributes: []string{"aggregatetuple"}, cursor: &bookmarks.Aggregatetuple},
+	}
+	if inp.AlgoKey != "" {
+		for _, reader := range readers {
+			reader.attributes = append(reader.attributes, inp.AlgoKey)
+		}
 	}
-	for _, compositeTraintupleKey := range compositeTraintupleKeys {
-		var outputModel outputModelListItem
-		var out outputCompositeTraintuple
 
-		out, err = getOutputCompositeTraintuple(db, compositeTraintupleKey)
-		if err != nil {
-			return
+	for scanned := 0; len(outModels) < OutputPageSize && scanned < queryModelsScanLimit; scanned++ {
+		// take the first tuple of all indexes, by algo key then tuple key
+		var current *modelIndexReader
+		var currentKey string
+		var currentTuple GenericTuple
+		for _, reader := range readers {
+			key, tuple, _err := reader.peek(db)
+			if _err != nil {
+				return outModels, "", _err
+			}
+			if key == "" {
+				continue
+			}
+			if current == nil || tuple.AlgoKey < currentTuple.AlgoKey || (tuple.AlgoKey == currentTuple.AlgoKey && key < currentKey) {
+				current, currentKey, currentTuple = reader, key, tuple
+			}
+		}
+		if current == nil {
+			// all the indexes are exhausted
+			return outModels, "", nil
+		}
+		current.next()
+
+		match, _err := inp.match(db, currentKey, currentTuple)
+		if _err != nil {
+			return outModels, "", _err
+		}
+		if !match {
+			continue
+		}
+		outputModel, _err := getOutputModelListItem(db, currentKey, currentTuple.AssetType)
+		if _err != nil {
+			return outModels, "", _err
 		}
-		outputModel.CompositeTraintuple = &out
 		outModels = append(outModels, outputModel)
 	}
 
-	// populate from composite traintuples
-	aggregatetupleKeys, aggregatetupleBookmark, err := db.GetIndexKeysWithPagination("aggregatetuple~algo~key", []string{"aggregatetuple"}, OutputPageSize/3, bookmarks.Aggregatetuple)
-	bookmarks.Aggregatetuple = aggregatetupleBookmark
-
+	bookmarkBytes, err := json.Marshal(bookmarks)
 	if err != nil {
 		return
 	}
-	for _, aggregatetupleKey := range aggregatetupleKeys {
-		var outputModel outputModelListItem
-		var out outputAggregatetuple
+	return outModels, string(bookmarkBytes), nil
+}
 
-		out, err = getOutputAggregatetuple(db, aggregatetupleKey)
-		if err != nil {
-			return
-		}
+// getOutputModelListItem returns the output of a tuple producing a model
+func getOutputModelListItem(db *LedgerDB, key string, assetType AssetType) (outputModel outputModelListItem, err error) {
+	switch assetType {
+	case TraintupleType:
+		var out outputTraintuple
+		out, err = getOutputTraintuple(db, key)
+		outputModel.Traintuple = &out
+	case CompositeTraintupleType:
+		var out outputCompositeTraintuple
+		out, err = getOutputCompositeTraintuple(db, key)
+		outputModel.CompositeTraintuple = &out
+	case AggregatetupleType:
+		var out outputAggregatetuple
+		out, err = getOutputAggregatetuple(db, key)
 		outputModel.Aggregatetuple = &out
-		outModels = append(outModels, outputModel)
+	default:
+		err = errors.Internal("getOutputModelListItem: unsupported asset type %s", assetType)
 	}
-
-	bookmarkBytes, err := json.Marshal(bookmarks)
-	bookmark = string(bookmarkBytes)
 	return
 }
 
@@ -217,13 +390,14 @@ func queryModel(db *LedgerDB, args []string) (outputModel, error) {
 	model := outputModel{
 		Key: modelKey,
 	}
+	var permissions Permissions
 
 	if tupleType == TraintupleType {
 		tuple, err := db.GetTraintuple(tupleKey)
 		if err != nil {
 			return model, errors.Internal(err, "getModel: cannot get traintuple")
 		}
-		model.Permissions.Fill(tuple.Permissions)
+		permissions = tuple.Permissions
 		model.Owner = tuple.Dataset.Worker
 		model.StorageAddress = tuple.OutModel.StorageAddress
 	}
@@ -233,7 +407,7 @@ func queryModel(db *LedgerDB, args []string) (outputModel, error) {
 		if err != nil {
 			return model, errors.Internal(err, "getModel: cannot get aggregatetuple")
 		}
-		model.Permissions.Fill(tuple.Permissions)
+		permissions = tuple.Permissions
 		model.Owner = tuple.Worker
 		model.StorageAddress = tuple.OutModel.StorageAddress
 	}
@@ -246,17 +420,160 @@ func queryModel(db *LedgerDB, args []string) (outputModel, error) {
 		// if `modelKey` refers to the head out-model, return the head out-model permissions
 		// if `modelKey` refers to the trunk out-model, default to "public processable")
 		if tuple.OutHeadModel.OutModel.Key == modelKey {
-			model.Permissions.Fill(tuple.OutHeadModel.Permissions)
-			model.StorageAddress = ""
+			permissions = tuple.OutHeadModel.Permissions
+			if tuple.OutHeadModel.StorageAddressHash != "" {
+				model.StorageAddress = getPrivateHeadModelStorageAddress(db, modelKey, tuple.Dataset.Worker)
+			}
 		} else {
-			model.Permissions.Fill(tuple.OutTrunkModel.Permissions)
+			permissions = tuple.OutTrunkModel.Permissions
 			model.StorageAddress = tuple.OutTrunkModel.OutModel.StorageAddress
 		}
 		model.Owner = tuple.Dataset.Worker
 	}
+
+	if tupleType == ExternalModelType {
+		externalModel, err := db.GetExternalModel(tupleKey)
+		if err != nil {
+			return model, errors.Internal(err, "getModel: cannot get external model")
+		}
+		permissions = externalModel.Permissions
+		model.Owner = externalModel.Owner
+		model.StorageAddress = externalModel.StorageAddress
+	}
+	model.Permissions.Fill(permissions)
+
+	node, err := GetTxCreator(db.cc)
+	if err != nil {
+		return model, err
+	}
+	allowed, err := permissions.CanDownload(db, model.Owner, node)
+	if err != nil {
+		return model, err
+	}
+	if !allowed {
+		model.StorageAddress = ""
+	}
+
+	deletion, err := db.GetModelDeletion(modelKey)
+	if err != nil {
+		return model, err
+	}
+	if deletion != nil {
+		// the files are gone, there is nothing left to download
+		model.Deleted = true
+		model.DeletedAt = deletion.DeletedAt
+		model.StorageAddress = ""
+	}
 	return model, nil
 }
 
+type queryWorkerTasksBookmark struct {
+	Status   string `json:"status"`
+	Bookmark string `json:"bookmark"`
+}
+
+// queryWorkerTasks returns the tuples of all types assigned to a worker with
+// one of the requested statuses. Tuples are listed status after status in the
+// requested order and, for each status, ordered by compute plan and rank.
+// The returned bookmark holds the status being listed and its index bookmark.
+func queryWorkerTasks(db *LedgerDB, args []string) (outTasks []outputWorkerTask, bookmark string, err error) {
+	inp := inputQueryWorkerTasks{}
+	outTasks = []outputWorkerTask{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+
+	start := 0
+	bookmarks := queryWorkerTasksBookmark{}
+	if inp.Bookmark != "" {
+		err = json.Unmarshal([]byte(inp.Bookmark), &bookmarks)
+		if err != nil {
+			return outTasks, "", errors.BadRequest(err, "invalid bookmark")
+		}
+		start = -1
+		for i, status := range inp.Statuses {
+			if status == bookmarks.Status {
+				start = i
+				break
+			}
+		}
+		if start == -1 {
+			return outTasks, "", errors.BadRequest("invalid bookmark: status %s not requested", bookmarks.Status)
+		}
+	}
+
+	pageSize := OutputPageSize
+	for i := start; i < len(inp.Statuses); i++ {
+		status := inp.Statuses[i]
+		statusBookmark := ""
+		if i == start {
+			statusBookmark = bookmarks.Bookmark
+		}
+		attributes := []string{"task", inp.Worker, status}
+		if inp.ComputePlanKey != "" {
+			attributes = append(attributes, inp.ComputePlanKey)
+		}
+
+		var keys []string
+		keys, statusBookmark, err = db.GetIndexKeysWithPagination("task~worker~status~cp~rank~key", attributes, int32(pageSize), statusBookmark)
+		if err != nil {
+			return
+		}
+		for _, key := range keys {
+			var task outputWorkerTask
+			task, err = getOutputWorkerTask(db, key)
+			if err != nil {
+				return
+			}
+			outTasks = append(outTasks, task)
+		}
+
+		pageSize -= len(keys)
+		if pageSize == 0 {
+			// the page ends exactly with the last requested status: there is nothing left
+			if statusBookmark == "" && i+1 == len(inp.Statuses) {
+				return outTasks, "", nil
+			}
+			bookmarks = queryWorkerTasksBookmark{Status: status, Bookmark: statusBookmark}
+			if statusBookmark == "" && i+1 < len(inp.Statuses) {
+				bookmarks = queryWorkerTasksBookmark{Status: inp.Statuses[i+1]}
+			}
+			bookmarkBytes, _ := json.Marshal(bookmarks)
+			return outTasks, string(bookmarkBytes), nil
+		}
+	}
+	return outTasks, "", nil
+}
+
+func getOutputWorkerTask(db *LedgerDB, key string) (out outputWorkerTask, err error) {
+	tupleType, err := db.GetAssetType(key)
+	if err != nil {
+		return
+	}
+	switch tupleType {
+	case TraintupleType:
+		var tuple outputTraintuple
+		tuple, err = getOutputTraintuple(db, key)
+		out.Traintuple = &tuple
+	case CompositeTraintupleType:
+		var tuple outputCompositeTraintuple
+		tuple, err = getOutputCompositeTraintuple(db, key)
+		out.CompositeTraintuple = &tuple
+	case AggregatetupleType:
+		var tuple outputAggregatetuple
+		tuple, err = getOutputAggregatetuple(db, key)
+		out.Aggregatetuple = &tuple
+	case TesttupleType:
+		var tuple outputTesttuple
+		tuple, err = getOutputTesttuple(db, key)
+		out.Testtuple = &tuple
+	default:
+		err = errors.Internal("asset %s is not a tuple (type %s)", key, tupleType)
+	}
+	return
+}
+
 // ----------------------------------------------------------
 // Utils for smartcontracts related to  multiple tuple types
 // ----------------------------------------------------------
@@ -273,11 +590,16 @@ func validateTupleOwner(db *LedgerDB, worker string) error {
 }
 
 // check validity of traintuple update: consistent status and agent submitting the transaction
-func checkUpdateTuple(db *LedgerDB, worker string, oldStatus string, newStatus string) error {
+// requeue is only set by the lease reaper, to send a tuple whose lease has expired back to the queue
+func checkUpdateTuple(db *LedgerDB, worker string, oldStatus string, newStatus string, requeue bool) error {
 	if StatusAborted == newStatus {
 		return nil
 	}
 
+	if requeue && oldStatus == StatusDoing && newStatus == StatusTodo {
+		return nil
+	}
+
 	statusPossibilities := map[string]string{
 		StatusWaiting: StatusTodo,
 		StatusTodo:    StatusDoing,
@@ -322,3 +644,51 @@ func determineTupleStatus(db *LedgerDB, tupleStatus, computePlanKey string) (str
 func createModelIndex(db *LedgerDB, modelKey, tupleKey string) error {
 	return db.CreateIndex("tuple~modelKey~key", []string{"tuple", modelKey, tupleKey})
 }
+
+// createModelChecksumIndex records the checksum of an out-model reported by a tuple
+func createModelChecksumIndex(db *LedgerDB, checksum, tupleKey string) error {
+	return db.CreateIndex("model~checksum~tupleKey", []string{"model", checksum, tupleKey})
+}
+
+// checkOutModel verifies that an out-model reported by the worker of a tuple is not
+// already bound to another tuple. Unless allowChecksumReuse is set, its checksum
+// must not have been reported by another tuple either.
+func checkOutModel(db *LedgerDB, tupleKey, modelKey, checksum string, allowChecksumReuse bool) error {
+	tupleKeys, err := db.GetIndexKeys("tuple~modelKey~key", []string{"tuple", modelKey})
+	if err != nil {
+		return err
+	}
+	for _, key := range tupleKeys {
+		if key != tupleKey {
+			return errors.Conflict("out-model %s is already bound to asset %s", modelKey, key).WithKey(key)
+		}
+	}
+	if allowChecksumReuse {
+		return nil
+	}
+	tupleKeys, err = db.GetIndexKeys("model~checksum~tupleKey", []string{"model", checksum})
+	if err != nil {
+		return err
+	}
+	for _, key := range tupleKeys {
+		if key != tupleKey {
+			return errors.Conflict("checksum %s of out-model %s was already reported by tuple %s, set allow_checksum_reuse to reuse it", checksum, modelKey, key).WithKey(key)
+		}
+	}
+	return nil
+}
+
+// createTaskIndex registers a tuple in the worker task queue index.
+// The rank is zero-padded so that tasks are sorted by rank within a compute plan.
+func createTaskIndex(db *LedgerDB, worker, status, computePlanKey string, rank int, tupleKey string) error {
+	return db.CreateIndex("task~worker~status~cp~rank~key", []string{"task", worker, status, computePlanKey, fmt.Sprintf("%010d", rank), tupleKey})
+}
+
+// updateTaskIndex moves a tuple from its old status to its new one in the
+// worker task queue index
+func updateTaskIndex(db *LedgerDB, worker, oldStatus, newStatus, computePlanKey string, rank int, tupleKey string) error {
+	indexName := "task~worker~status~cp~rank~key"
+	oldAttributes := []string{"task", worker, oldStatus, computePlanKey, fmt.Sprintf("%010d", rank), tupleKey}
+	newAttributes := []string{"task", worker, newStatus, computePlanKey, fmt.Sprintf("%010d", rank), tupleKey}
+	return db.UpdateIndex(indexName, oldAttributes, newAttributes)
+}
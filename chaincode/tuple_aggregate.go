@@ -40,20 +40,33 @@ func (tuple *Aggregatetuple) SetFromInput(db *LedgerDB, inp inputAggregatetuple)
 	tuple.Creator = creator
 	tuple.Metadata = inp.Metadata
 	tuple.Tag = inp.Tag
+	tuple.KeepModel = inp.KeepModel
 	tuple.ComputePlanKey = inp.ComputePlanKey
 	algo, err := db.GetAggregateAlgo(inp.AlgoKey)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve algo with key %s", inp.AlgoKey)
 	}
-	if !algo.Permissions.CanProcess(algo.Owner, creator) {
+	allowed, err := algo.Permissions.CanProcess(db, algo.Owner, creator)
+	if err != nil {
+		return err
+	}
+	if !allowed {
 		return errors.Forbidden("not authorized to process algo %s", inp.AlgoKey)
 	}
 	tuple.AlgoKey = inp.AlgoKey
 	// Check if worker is a valid node
-	_, err = db.GetNode(inp.Worker)
+	worker, err := db.GetNode(inp.Worker)
 	if err != nil {
 		return errors.BadRequest(err, "could not retrieve worker %s", inp.Worker)
 	}
+	if worker.Inactive {
+		return errors.BadRequest("worker %s is deregistered", inp.Worker)
+	}
+	// Check if worker can process every in-model, using the trunk out-model
+	// when the parent is a composite traintuple
+	if err = checkInModelsPermissions(db, inp.Worker, inp.InModels, false); err != nil {
+		return err
+	}
 	tuple.Worker = inp.Worker
 	return nil
 }
@@ -98,6 +111,12 @@ func (tuple *Aggregatetuple) SetFromParents(db *LedgerDB, inModels []string) err
 				parentPermissions = tuple.Permissions
 				parentStatuses = append(parentStatuses, tuple.Status)
 			}
+		case ExternalModelType:
+			model, err := db.GetExternalModel(parentTraintupleKey)
+			if err == nil {
+				parentPermissions = model.Permissions
+				parentStatuses = append(parentStatuses, StatusDone)
+			}
 		default:
 			return errors.Internal("aggregate.SetFromParents: Unsupported parent type %s", parentType)
 		}
@@ -105,9 +124,15 @@ func (tuple *Aggregatetuple) SetFromParents(db *LedgerDB, inModels []string) err
 		if err != nil {
 			return errors.Internal("could not retrieve traintuple type with key %s - %s", parentTraintupleKey, err.Error())
 		}
+		if err := checkInModelNotDeleted(db, parentTraintupleKey, false); err != nil {
+			return err
+		}
 
 		inModelKeys = append(inModelKeys, parentTraintupleKey)
-		permissions = MergePermissions(permissions, parentPermissions)
+		permissions, err = MergePermissions(db, permissions, parentPermissions)
+		if err != nil {
+			return err
+		}
 	}
 	tuple.Status = determineStatusFromInModels(parentStatuses)
 	tuple.InModelKeys = inModelKeys
@@ -193,6 +218,12 @@ func (tuple *Aggregatetuple) Save(db *LedgerDB, aggregatetupleKey string) error
 			return err
 		}
 	}
+	if err := createComputePlanTupleIndex(db, tuple.ComputePlanKey, AggregatetupleType, tuple.Status, aggregatetupleKey); err != nil {
+		return err
+	}
+	if err := createTaskIndex(db, tuple.Worker, tuple.Status, tuple.ComputePlanKey, tuple.Rank, aggregatetupleKey); err != nil {
+		return err
+	}
 	if tuple.Tag != "" {
 		err := db.CreateIndex("aggregatetuple~tag~key", []string{"aggregatetuple", tuple.Tag, aggregatetupleKey})
 		if err != nil {
@@ -280,6 +311,31 @@ func logStartAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, err
 	if err = aggregatetuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
+	if _, err = putTupleLease(db, inp.Key, aggregatetuple.Worker); err != nil {
+		return
+	}
+	err = o.Fill(db, aggregatetuple)
+	return
+}
+
+// logProgressAggregate reports the progress of a aggregatetuple in the doing state
+func logProgressAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, err error) {
+	inp := inputLogProgress{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+
+	aggregatetuple, err := db.GetAggregatetuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
+		return
+	}
+	if err = putTupleProgress(db, inp, aggregatetuple.Status); err != nil {
+		return
+	}
 	err = o.Fill(db, aggregatetuple)
 	return
 }
@@ -304,6 +360,10 @@ func logFailAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, err
 	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
 		return
 	}
+	aggregatetuple.LogHash, err = appendPrivateTupleLog(db, inp.Key, aggregatetuple.Worker, aggregatetuple.LogHash)
+	if err != nil {
+		return
+	}
 	if err = aggregatetuple.commitStatusUpdate(db, inp.Key, status); err != nil {
 		return
 	}
@@ -340,6 +400,17 @@ func logSuccessAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, e
 		return
 	}
 
+	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
+		return
+	}
+	if err = checkOutModel(db, aggregatetupleKey, inp.OutModel.Key, inp.OutModel.Checksum, inp.AllowChecksumReuse); err != nil {
+		return
+	}
+	aggregatetuple.LogHash, err = appendPrivateTupleLog(db, aggregatetupleKey, aggregatetuple.Worker, aggregatetuple.LogHash)
+	if err != nil {
+		return
+	}
+
 	aggregatetuple.OutModel = &KeyChecksumAddress{
 		Key:            inp.OutModel.Key,
 		Checksum:       inp.OutModel.Checksum,
@@ -350,8 +421,8 @@ func logSuccessAggregate(db *LedgerDB, args []string) (o outputAggregatetuple, e
 	if err != nil {
 		return
 	}
-
-	if err = validateTupleOwner(db, aggregatetuple.Worker); err != nil {
+	err = createModelChecksumIndex(db, inp.OutModel.Checksum, aggregatetupleKey)
+	if err != nil {
 		return
 	}
 
@@ -502,6 +573,12 @@ func getOutputAggregatetuples(db *LedgerDB, aggregatetupleKeys []string) (outAgg
 
 // commitStatusUpdate update the aggregatetuple status in the ledger
 func (tuple *Aggregatetuple) commitStatusUpdate(db *LedgerDB, aggregatetupleKey string, newStatus string) error {
+	return tuple.updateStatus(db, aggregatetupleKey, newStatus, false)
+}
+
+// updateStatus updates the aggregatetuple status in the ledger. Setting requeue allows
+// to send a "doing" tuple back to "todo", which only the lease reaper does.
+func (tuple *Aggregatetuple) updateStatus(db *LedgerDB, aggregatetupleKey string, newStatus string, requeue bool) error {
 	if tuple.Status == newStatus {
 		return nil
 	}
@@ -511,7 +588,7 @@ func (tuple *Aggregatetuple) commitStatusUpdate(db *LedgerDB, aggregatetupleKey
 		return nil
 	}
 
-	if err := tuple.validateNewStatus(db, newStatus); err != nil {
+	if err := tuple.validateNewStatus(db, newStatus, requeue); err != nil {
 		return errors.Internal("update aggregatetuple %s failed: %s", aggregatetupleKey, err.Error())
 	}
 
@@ -528,6 +605,12 @@ func (tuple *Aggregatetuple) commitStatusUpdate(db *LedgerDB, aggregatetupleKey
 	if err := db.UpdateIndex(indexName, oldAttributes, newAttributes); err != nil {
 		return err
 	}
+	if err := updateComputePlanTupleIndex(db, tuple.ComputePlanKey, AggregatetupleType, oldStatus, newStatus, aggregatetupleKey); err != nil {
+		return err
+	}
+	if err := updateTaskIndex(db, tuple.Worker, oldStatus, newStatus, tuple.ComputePlanKey, tuple.Rank, aggregatetupleKey); err != nil {
+		return err
+	}
 	if err := UpdateComputePlanState(db, tuple.ComputePlanKey, newStatus, aggregatetupleKey, tuple.Worker); err != nil {
 		return err
 	}
@@ -536,9 +619,9 @@ func (tuple *Aggregatetuple) commitStatusUpdate(db *LedgerDB, aggregatetupleKey
 }
 
 // validateNewStatus verifies that the new status is consistent with the tuple current status
-func (tuple *Aggregatetuple) validateNewStatus(db *LedgerDB, status string) error {
+func (tuple *Aggregatetuple) validateNewStatus(db *LedgerDB, status string, requeue bool) error {
 	// check validity of worker and change of status
-	if err := checkUpdateTuple(db, tuple.Worker, tuple.Status, status); err != nil {
+	if err := checkUpdateTuple(db, tuple.Worker, tuple.Status, status, requeue); err != nil {
 		return err
 	}
 	return nil
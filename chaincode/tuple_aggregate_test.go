@@ -511,6 +511,38 @@ func TestAggregatetuplePermissions(t *testing.T) {
 		"the aggregate tuple permissions should be the intersect of the in-model permissions")
 }
 
+func TestAggregatetupleWorker(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	registerWorker(mockStub, "nodeA")
+	registerWorker(mockStub, "nodeB")
+
+	inp := inputCompositeTraintuple{Key: RandomUUID()}
+	inp.fillDefaults()
+	inp.OutTrunkModelPermissions.Process.Public = false
+	inp.OutTrunkModelPermissions.Process.AuthorizedIDs = []string{"nodeA"}
+	resp := mockStub.MockInvoke(inp.getArgs())
+	require.EqualValues(t, 200, resp.Status, resp.Message)
+
+	inpAgg := inputAggregatetuple{}
+	inpAgg.fillDefaults()
+	inpAgg.InModels = []string{inp.Key}
+
+	inpAgg.Worker = "unknown"
+	resp = mockStub.MockInvoke(inpAgg.getArgs())
+	assert.EqualValues(t, 400, resp.Status, "the worker should be a registered node")
+
+	inpAgg.Worker = "nodeB"
+	resp = mockStub.MockInvoke(inpAgg.getArgs())
+	assert.EqualValues(t, 403, resp.Status, "the worker should be able to process the in-models")
+	assert.Contains(t, resp.Message, inp.Key)
+
+	inpAgg.Worker = "nodeA"
+	resp = mockStub.MockInvoke(inpAgg.getArgs())
+	assert.EqualValues(t, 200, resp.Status, resp.Message)
+}
+
 func TestAggregatetupleLogSuccessFail(t *testing.T) {
 	for _, status := range []string{StatusDone, StatusFailed} {
 		t.Run("TestAggregatetupleLog"+status, func(t *testing.T) {
@@ -0,0 +1,260 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+	"time"
+)
+
+// TupleLeaseDuration is the time a worker has to send a heartbeat for a tuple
+// in the "doing" state before it can be reaped.
+const TupleLeaseDuration = 30 * time.Minute
+
+// heartbeatTuple extends the lease held by the worker on a tuple it is processing
+func heartbeatTuple(db *LedgerDB, args []string) (resp outputTupleLease, err error) {
+	inp := inputKey{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	tuple, err := db.GetGenericTuple(inp.Key)
+	if err != nil {
+		return
+	}
+	if tuple.Status != StatusDoing {
+		return resp, errors.BadRequest("cannot extend the lease of tuple %s with status %s", inp.Key, tuple.Status)
+	}
+	lease, err := db.GetTupleLease(inp.Key)
+	if err != nil {
+		return
+	}
+	if err = validateTupleOwner(db, lease.Worker); err != nil {
+		return
+	}
+	lease, err = putTupleLease(db, inp.Key, lease.Worker)
+	if err != nil {
+		return
+	}
+	resp.Fill(inp.Key, lease)
+	return
+}
+
+// reapExpiredTuples moves the tuples whose lease has expired from "doing" to
+// "failed", or back to "todo" if requeue is set. The tuples are either those of a
+// compute plan, which only its creator can reap (see canReapComputePlan), or
+// the tuples listed by key, which only their creator can reap.
+func reapExpiredTuples(db *LedgerDB, args []string) (resp map[string][]string, err error) {
+	inp := inputReapExpiredTuples{}
+	err = AssetFromJSON(args, &inp)
+	if err != nil {
+		return
+	}
+	if inp.ComputePlanKey != "" && len(inp.Keys) > 0 {
+		return nil, errors.BadRequest("compute_plan_key and keys can't be set together")
+	}
+	txCreator, err := GetTxCreator(db.cc)
+	if err != nil {
+		return
+	}
+	now, err := GetTxTime(db.cc)
+	if err != nil {
+		return
+	}
+	newStatus := StatusFailed
+	if inp.Requeue {
+		newStatus = StatusTodo
+	}
+
+	reapedKeys := []string{}
+	if inp.ComputePlanKey == "" {
+		for _, tupleKey := range inp.Keys {
+			var tuple GenericTuple
+			tuple, err = db.GetGenericTuple(tupleKey)
+			if err != nil {
+				return
+			}
+			allowed := tuple.Creator == txCreator
+			if tuple.ComputePlanKey != "" {
+				var computePlan ComputePlan
+				computePlan, err = db.GetComputePlan(tuple.ComputePlanKey)
+				if err != nil {
+					return
+				}
+				allowed, err = canReapComputePlan(db, computePlan, txCreator)
+				if err != nil {
+					return
+				}
+			}
+			if !allowed {
+				return nil, errors.Forbidden("%s is not allowed to reap tuple %s", txCreator, tupleKey)
+			}
+			if tuple.Status != StatusDoing {
+				continue
+			}
+			var reaped bool
+			reaped, err = reapExpiredTuple(db, tupleKey, tuple.AssetType, newStatus, now, txCreator)
+			if err != nil {
+				return
+			}
+			if reaped {
+				reapedKeys = append(reapedKeys, tupleKey)
+			}
+		}
+		return map[string][]string{"keys": reapedKeys}, nil
+	}
+
+	computePlan, err := db.GetComputePlan(inp.ComputePlanKey)
+	if err != nil {
+		return
+	}
+	allowed, err := canReapComputePlan(db, computePlan, txCreator)
+	if err != nil {
+		return
+	}
+	if !allowed {
+		return nil, errors.Forbidden("%s is not allowed to reap the tuples of compute plan %s", txCreator, inp.ComputePlanKey)
+	}
+	tupleTypes := []AssetType{TraintupleType, CompositeTraintupleType, AggregatetupleType, TesttupleType}
+	for _, tupleType := range tupleTypes {
+		var tupleKeys []string
+		tupleKeys, err = db.GetIndexKeys("computePlan~key~type~status~tupleKey", []string{"computePlan", inp.ComputePlanKey, tupleType.String(), StatusDoing})
+		if err != nil {
+			return
+		}
+		for _, tupleKey := range tupleKeys {
+			var reaped bool
+			reaped, err = reapExpiredTuple(db, tupleKey, tupleType, newStatus, now, txCreator)
+			if err != nil {
+				return
+			}
+			if reaped {
+				reapedKeys = append(reapedKeys, tupleKey)
+			}
+		}
+	}
+	return map[string][]string{"keys": reapedKeys}, nil
+}
+
+// reapExpiredTuple reaps a "doing" tuple if its lease has expired and reports
+// whether it did
+func reapExpiredTuple(db *LedgerDB, tupleKey string, tupleType AssetType, newStatus string, now time.Time, txCreator string) (bool, error) {
+	// Tuples started before leases were introduced are left untouched
+	exists, err := db.KeyExists(getTupleLeaseKey(tupleKey))
+	if err != nil || !exists {
+		return false, err
+	}
+	lease, err := db.GetTupleLease(tupleKey)
+	if err != nil {
+		return false, err
+	}
+	expiresAt := time.Unix(lease.ExpiresAt, 0).UTC()
+	if now.Before(expiresAt) {
+		return false, nil
+	}
+	log := fmt.Sprintf("Lease of worker %s expired at %s, tuple reaped to %s by %s; ", lease.Worker, expiresAt.Format(time.RFC3339), newStatus, txCreator)
+	if err = reapTuple(db, tupleKey, tupleType, newStatus, log); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// canReapComputePlan checks that a node created the compute plan. Compute plans
+// created before their creator was recorded fall back to the creators of their tuples.
+func canReapComputePlan(db *LedgerDB, computePlan ComputePlan, node string) (bool, error) {
+	if computePlan.Creator != "" {
+		return node == computePlan.Creator, nil
+	}
+	tupleKeys := append([]string{}, computePlan.TraintupleKeys...)
+	tupleKeys = append(tupleKeys, computePlan.CompositeTraintupleKeys...)
+	tupleKeys = append(tupleKeys, computePlan.AggregatetupleKeys...)
+	for _, tupleKey := range tupleKeys {
+		tuple, err := db.GetGenericTuple(tupleKey)
+		if err != nil {
+			return false, err
+		}
+		if tuple.Creator == node {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// reapTuple appends the log to the private log of the tuple and commits its new status
+func reapTuple(db *LedgerDB, tupleKey string, tupleType AssetType, newStatus string, log string) error {
+	switch tupleType {
+	case TraintupleType:
+		tuple, err := db.GetTraintuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		tuple.LogHash, err = putPrivateTupleLogEntry(db, tupleKey, tuple.Dataset.Worker, tuple.LogHash, log)
+		if err != nil {
+			return err
+		}
+		return tuple.updateStatus(db, tupleKey, newStatus, newStatus == StatusTodo)
+	case CompositeTraintupleType:
+		tuple, err := db.GetCompositeTraintuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		tuple.LogHash, err = putPrivateTupleLogEntry(db, tupleKey, tuple.Dataset.Worker, tuple.LogHash, log)
+		if err != nil {
+			return err
+		}
+		return tuple.updateStatus(db, tupleKey, newStatus, newStatus == StatusTodo)
+	case AggregatetupleType:
+		tuple, err := db.GetAggregatetuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		tuple.LogHash, err = putPrivateTupleLogEntry(db, tupleKey, tuple.Worker, tuple.LogHash, log)
+		if err != nil {
+			return err
+		}
+		return tuple.updateStatus(db, tupleKey, newStatus, newStatus == StatusTodo)
+	case TesttupleType:
+		tuple, err := db.GetTesttuple(tupleKey)
+		if err != nil {
+			return err
+		}
+		tuple.LogHash, err = putPrivateTupleLogEntry(db, tupleKey, tuple.Dataset.Worker, tuple.LogHash, log)
+		if err != nil {
+			return err
+		}
+		return tuple.updateStatus(db, tupleKey, newStatus, newStatus == StatusTodo)
+	}
+	return errors.Internal("cannot reap asset %s of type %s", tupleKey, tupleType)
+}
+
+// putTupleLease grants the worker a lease on the tuple expiring
+// TupleLeaseDuration after the transaction timestamp
+func putTupleLease(db *LedgerDB, tupleKey string, worker string) (TupleLease, error) {
+	now, err := GetTxTime(db.cc)
+	if err != nil {
+		return TupleLease{}, err
+	}
+	lease := TupleLease{
+		Worker:    worker,
+		ExpiresAt: now.Add(TupleLeaseDuration).Unix(),
+	}
+	return lease, db.Put(getTupleLeaseKey(tupleKey), lease)
+}
+
+// getTupleLeaseKey returns the lease key for a given tuple
+func getTupleLeaseKey(tupleKey string) string {
+	return fmt.Sprintf("tuple~%v~lease", tupleKey)
+}
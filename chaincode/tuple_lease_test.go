@@ -0,0 +1,166 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"chaincode/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleLease(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+	key := out.TraintupleKeys[0]
+
+	_, err = heartbeatTuple(db, keyToArgs(key))
+	assert.Error(t, err, "a todo tuple has no lease")
+
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: key}))
+	require.NoError(t, err)
+	lease, err := db.GetTupleLease(key)
+	require.NoError(t, err)
+	assert.Equal(t, workerA, lease.Worker)
+
+	mockStub.MockTransactionStart("43")
+	db = NewLedgerDB(mockStub)
+	heartbeat, err := heartbeatTuple(db, keyToArgs(key))
+	assert.NoError(t, err)
+	assert.True(t, heartbeat.ExpiresAt > lease.ExpiresAt, "the heartbeat should extend the lease")
+
+	reaped, err := reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{ComputePlanKey: out.Key, Requeue: true}))
+	assert.NoError(t, err)
+	assert.Len(t, reaped["keys"], 0, "the lease has not expired yet")
+
+	// Let the lease expire and send the tuple back to the queue
+	mockStub.TxTimestamp.Seconds += int64(TupleLeaseDuration.Seconds())
+	mockStub.MockTransactionStart("44")
+	db = NewLedgerDB(mockStub)
+	reaped, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{ComputePlanKey: out.Key, Requeue: true}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, reaped["keys"])
+	traintuple, err := db.GetTraintuple(key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusTodo, traintuple.Status)
+	assert.Empty(t, traintuple.Log, "the reaper logs to the private log")
+	tupleLog, err := queryTupleLog(db, keyToArgs(key))
+	assert.NoError(t, err)
+	assert.Contains(t, tupleLog.Log, "Lease of worker")
+	assert.Equal(t, traintuple.LogHash, tupleLog.LogHash)
+
+	// Start it again and let it expire for good
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: key}))
+	require.NoError(t, err)
+	mockStub.TxTimestamp.Seconds += int64(TupleLeaseDuration.Seconds())
+	mockStub.MockTransactionStart("45")
+	db = NewLedgerDB(mockStub)
+	reaped, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{ComputePlanKey: out.Key}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{key}, reaped["keys"])
+	traintuple, err = db.GetTraintuple(key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, traintuple.Status)
+	tupleLog, err = queryTupleLog(db, keyToArgs(key))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, strings.Count(tupleLog.Log, "Lease of worker"), "each reap should be logged")
+	cp, err := db.GetComputePlan(out.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, cp.State.Status)
+}
+
+func TestRequeueOnlyByReaper(t *testing.T) {
+	assert.Error(t, checkUpdateTuple(nil, workerA, StatusDoing, StatusTodo, false))
+	assert.NoError(t, checkUpdateTuple(nil, workerA, StatusDoing, StatusTodo, true))
+}
+
+func TestReapComputePlanWithoutCreator(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+
+	// compute plans created before the creator was recorded
+	computePlan := ComputePlan{}
+	require.NoError(t, db.Get(out.Key, &computePlan))
+	computePlan.Creator = ""
+	require.NoError(t, db.Put(out.Key, computePlan))
+
+	mockStub.Creator = workerB
+	_, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{ComputePlanKey: out.Key}))
+	assert.Error(t, err, "only the creator of the compute plan tuples can reap them")
+	mockStub.Creator = workerA
+	_, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{ComputePlanKey: out.Key}))
+	assert.NoError(t, err)
+}
+
+func TestReapTupleOutsideComputePlan(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputTraintuple{Key: RandomUUID()}
+	inp.createDefault()
+	_, err := createTraintuple(db, assetToArgs(inp))
+	require.NoError(t, err)
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: inp.Key}))
+	require.NoError(t, err)
+
+	_, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{Keys: []string{inp.Key}, ComputePlanKey: RandomUUID()}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "a compute plan and keys can't be reaped together")
+
+	reaped, err := reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{Keys: []string{inp.Key}, Requeue: true}))
+	assert.NoError(t, err)
+	assert.Len(t, reaped["keys"], 0, "the lease has not expired yet")
+
+	// Let the lease expire
+	mockStub.TxTimestamp.Seconds += int64(TupleLeaseDuration.Seconds())
+	mockStub.MockTransactionStart("43")
+	db = NewLedgerDB(mockStub)
+	mockStub.Creator = workerB
+	_, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{Keys: []string{inp.Key}}))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode(), "only the creator of the tuple can reap it")
+	mockStub.Creator = workerA
+	reaped, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{Keys: []string{inp.Key}}))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{inp.Key}, reaped["keys"])
+	traintuple, err := db.GetTraintuple(inp.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusFailed, traintuple.Status)
+	tupleLog, err := queryTupleLog(db, keyToArgs(inp.Key))
+	assert.NoError(t, err)
+	assert.Contains(t, tupleLog.Log, "Lease of worker")
+
+	// a tuple which is not doing anymore is left untouched
+	reaped, err = reapExpiredTuples(db, assetToArgs(inputReapExpiredTuples{Keys: []string{inp.Key}}))
+	assert.NoError(t, err)
+	assert.Len(t, reaped["keys"], 0)
+}
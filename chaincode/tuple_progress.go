@@ -0,0 +1,42 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"chaincode/errors"
+	"fmt"
+)
+
+// putTupleProgress stores the progress reported on a tuple in the "doing" state
+func putTupleProgress(db *LedgerDB, inp inputLogProgress, status string) error {
+	if status != StatusDoing {
+		return errors.BadRequest("cannot report progress on tuple %s with status %s", inp.Key, status)
+	}
+	progress := TupleProgress{
+		Epoch:   inp.Epoch,
+		Step:    inp.Step,
+		Percent: inp.Percent,
+		Metrics: inp.Metrics,
+	}
+	if progress.Metrics == nil {
+		progress.Metrics = map[string]float64{}
+	}
+	return db.Put(getTupleProgressKey(inp.Key), progress)
+}
+
+// getTupleProgressKey returns the progress key for a given tuple
+func getTupleProgressKey(tupleKey string) string {
+	return fmt.Sprintf("tuple~%v~progress", tupleKey)
+}
@@ -0,0 +1,56 @@
+// Copyright 2018 Owkin, inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogProgressTrain(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "traintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	progress := inputLogProgress{
+		Key:     traintupleKey,
+		Epoch:   2,
+		Step:    120,
+		Percent: 40,
+		Metrics: map[string]float64{"loss": 0.42},
+	}
+	_, err := logProgressTrain(db, assetToArgs(progress))
+	assert.Error(t, err, "progress cannot be reported on a todo traintuple")
+
+	_, err = logStartTrain(db, assetToArgs(inputKey{Key: traintupleKey}))
+	require.NoError(t, err)
+	out, err := logProgressTrain(db, assetToArgs(progress))
+	assert.NoError(t, err)
+	require.NotNil(t, out.Progress)
+	assert.Equal(t, TupleProgress{Epoch: 2, Step: 120, Percent: 40, Metrics: map[string]float64{"loss": 0.42}}, *out.Progress)
+
+	out, err = queryTraintuple(db, keyToArgs(traintupleKey))
+	assert.NoError(t, err)
+	require.NotNil(t, out.Progress)
+	assert.Equal(t, float32(40), out.Progress.Percent)
+
+	progress.Percent = 140
+	_, err = logProgressTrain(db, assetToArgs(progress))
+	assert.Error(t, err, "the percentage cannot exceed 100")
+}
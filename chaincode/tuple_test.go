@@ -16,8 +16,11 @@ package main
 
 import (
 	"encoding/json"
+	"net/http"
 	"testing"
 
+	"chaincode/errors"
+
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -248,7 +251,7 @@ func TestQueryModel(t *testing.T) {
 	// Pass the traintuple to "done"
 	mockStub.MockTransactionStart("42")
 	db := NewLedgerDB(mockStub)
-	traintupleToDone(t, db, traintupleKey)
+	traintupleToDone(t, db, traintupleKey, modelKey)
 	outTrain, err := queryTraintuple(db, keyToArgs(traintupleKey))
 	assert.NoError(t, err)
 	model, err := queryModel(db, keyToArgs(outTrain.OutModel.Key))
@@ -289,6 +292,66 @@ func TestQueryHeadModelPermissions(t *testing.T) {
 	assert.Contains(t, model.Permissions.Process.AuthorizedIDs, workerA)
 }
 
+func TestGrantHeadModelDownload(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerWorker(mockStub, workerB)
+	registerItem(t, *mockStub, "compositeTraintuple")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	_, err := logStartCompositeTrain(db, assetToArgs(inputKey{Key: compositeTraintupleKey}))
+	require.NoError(t, err)
+	success := inputLogSuccessCompositeTrain{}
+	success.Key = compositeTraintupleKey
+	success.fillDefaults()
+	mockStub.TransientMap = map[string][]byte{transientOutHeadModelStorageAddress: []byte(headModelAddress)}
+	_, err = logSuccessCompositeTrain(db, assetToArgs(success))
+	require.NoError(t, err)
+	mockStub.TransientMap = nil
+
+	// the head model address is kept out of the public state and the tuple outputs
+	assert.NotContains(t, string(mockStub.State[compositeTraintupleKey]), headModelAddress)
+	outTrain, err := queryCompositeTraintuple(db, keyToArgs(compositeTraintupleKey))
+	require.NoError(t, err)
+	assert.NotContains(t, string(assetToJSON(outTrain)), headModelAddress)
+
+	// and only revealed to its owner
+	model, err := queryModel(db, keyToArgs(headModelKey))
+	require.NoError(t, err)
+	assert.Equal(t, headModelAddress, model.StorageAddress)
+	mockStub.Creator = workerB
+	model, err = queryModel(db, keyToArgs(headModelKey))
+	require.NoError(t, err)
+	assert.Empty(t, model.StorageAddress)
+
+	// until it grants the download to other nodes
+	grant := inputGrantHeadModelDownload{Key: headModelKey, AuthorizedIDs: []string{workerB}}
+	_, err = grantHeadModelDownload(db, assetToArgs(grant))
+	assert.Equal(t, http.StatusForbidden, errors.Wrap(err).HTTPStatusCode())
+	mockStub.Creator = workerA
+	_, err = grantHeadModelDownload(db, assetToArgs(inputGrantHeadModelDownload{Key: trunkModelKey, AuthorizedIDs: []string{workerB}}))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode())
+	_, err = grantHeadModelDownload(db, assetToArgs(grant))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the address should be passed to share it")
+	mockStub.TransientMap = map[string][]byte{transientOutHeadModelStorageAddress: []byte("https://somewhere/else")}
+	_, err = grantHeadModelDownload(db, assetToArgs(grant))
+	assert.Equal(t, http.StatusBadRequest, errors.Wrap(err).HTTPStatusCode(), "the address should match the reported one")
+	mockStub.TransientMap = map[string][]byte{transientOutHeadModelStorageAddress: []byte(headModelAddress)}
+	_, err = grantHeadModelDownload(db, assetToArgs(grant))
+	require.NoError(t, err)
+	mockStub.TransientMap = nil
+
+	// the grantee peers read the address from the collection of their own node
+	delete(mockStub.PvtState, getImplicitCollection(workerA))
+	mockStub.Creator = workerB
+	model, err = queryModel(db, keyToArgs(headModelKey))
+	require.NoError(t, err)
+	assert.Equal(t, headModelAddress, model.StorageAddress)
+	assert.Contains(t, model.Permissions.Download.AuthorizedIDs, workerB)
+	assert.NotContains(t, model.Permissions.Process.AuthorizedIDs, workerB, "granting the download should not allow to process")
+}
+
 type ModelsResponse struct {
 	Results  []outputModelListItem `json:"results"`
 	Bookmark string                `json:"bookmark"`
@@ -301,7 +364,7 @@ func TestQueryModelsPagination(t *testing.T) {
 	resp, _ := registerItem(t, *mockStub, "algo")
 
 	// Add N + 1 traintuples
-	for i := 0; i < OutputPageSize/3+1; i++ {
+	for i := 0; i < OutputPageSize+1; i++ {
 		uuid, _ := GetNewUUID()
 		inpTraintuple := inputTraintuple{Key: uuid}
 		args := inpTraintuple.createDefault()
@@ -320,13 +383,13 @@ func TestQueryModelsPagination(t *testing.T) {
 
 	var models ModelsResponse
 
-	// 1st query (no bookmark) should return OutputPageSize/3 results
+	// 1st query (no bookmark) should return OutputPageSize results
 	args := [][]byte{[]byte("queryModels")}
 	resp = mockStub.MockInvoke(args)
 	assert.EqualValues(t, 200, resp.Status, "It should find the models without error ", resp.Message)
 	err := json.Unmarshal(resp.Payload, &models)
 	assert.NoError(t, err, "models should unmarshal without problem")
-	assert.Equal(t, OutputPageSize/3, len(models.Results))
+	assert.Equal(t, OutputPageSize, len(models.Results))
 	firstResult := models.Results[0].Traintuple.Key
 
 	// 2nd query (with bookmark) should return 1 result
@@ -341,4 +404,174 @@ func TestQueryModelsPagination(t *testing.T) {
 	// 2nd query should return different results from 1st query
 	newFirstResult := models.Results[0].Traintuple.Key
 	assert.NotEqual(t, newFirstResult, firstResult, "query results should be different")
+	assert.Empty(t, models.Bookmark, "all the models should be listed")
+}
+
+func TestQueryModelsFilters(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	traintuple := inputTraintuple{Key: RandomUUID()}
+	traintuple.createDefault()
+	_, err := createTraintuple(db, assetToArgs(traintuple))
+	require.NoError(t, err)
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+
+	// without filter, all the tuples are merged, ordered by algo then key
+	models, bookmark, err := queryModels(db, []string{})
+	require.NoError(t, err)
+	assert.Empty(t, bookmark)
+	require.Len(t, models, 7)
+	previous := ""
+	for _, model := range models {
+		var current string
+		switch {
+		case model.Traintuple != nil:
+			current = model.Traintuple.Algo.Key + model.Traintuple.Key
+		case model.CompositeTraintuple != nil:
+			current = model.CompositeTraintuple.Algo.Key + model.CompositeTraintuple.Key
+		case model.Aggregatetuple != nil:
+			current = model.Aggregatetuple.Algo.Key + model.Aggregatetuple.Key
+		}
+		assert.True(t, previous < current, "models should be ordered by algo then key")
+		previous = current
+	}
+
+	for name, tc := range map[string]struct {
+		filter   inputQueryModels
+		expected []string
+	}{
+		"compute plan": {
+			filter:   inputQueryModels{ComputePlanKey: out.Key},
+			expected: append(append([]string{}, out.CompositeTraintupleKeys...), out.AggregatetupleKeys...),
+		},
+		"algo": {
+			filter:   inputQueryModels{AlgoKey: aggregateAlgoKey},
+			expected: out.AggregatetupleKeys,
+		},
+		"worker": {
+			filter:   inputQueryModels{Worker: workerB},
+			expected: []string{out.CompositeTraintupleKeys[1], out.CompositeTraintupleKeys[3]},
+		},
+		"status": {
+			filter:   inputQueryModels{Status: StatusTodo},
+			expected: []string{traintuple.Key, out.CompositeTraintupleKeys[0], out.CompositeTraintupleKeys[1]},
+		},
+		"tag": {
+			filter:   inputQueryModels{Tag: "no such tag"},
+			expected: []string{},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			models, _, err := queryModels(db, assetToArgs(tc.filter))
+			require.NoError(t, err)
+			keys := []string{}
+			for _, model := range models {
+				switch {
+				case model.Traintuple != nil:
+					keys = append(keys, model.Traintuple.Key)
+				case model.CompositeTraintuple != nil:
+					keys = append(keys, model.CompositeTraintuple.Key)
+				case model.Aggregatetuple != nil:
+					keys = append(keys, model.Aggregatetuple.Key)
+				}
+			}
+			assert.ElementsMatch(t, tc.expected, keys)
+		})
+	}
+
+	_, _, err = queryModels(db, assetToArgs(inputQueryModels{Bookmark: "not a bookmark"}))
+	assert.Error(t, err)
+}
+
+func TestQueryModelsScanLimit(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := getMockStubForModelComposition(t, scc)
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, modelCompositionComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+
+	defer func(limit int) { queryModelsScanLimit = limit }(queryModelsScanLimit)
+	queryModelsScanLimit = 2
+
+	keys := []string{}
+	inp := inputQueryModels{Worker: workerB}
+	for calls := 0; ; calls++ {
+		require.True(t, calls < 10, "the listing should end")
+		models, bookmark, err := queryModels(db, assetToArgs(inp))
+		require.NoError(t, err)
+		assert.True(t, len(models) <= queryModelsScanLimit)
+		for _, model := range models {
+			require.NotNil(t, model.CompositeTraintuple)
+			keys = append(keys, model.CompositeTraintuple.Key)
+		}
+		if bookmark == "" {
+			break
+		}
+		inp.Bookmark = bookmark
+	}
+	assert.ElementsMatch(t, []string{out.CompositeTraintupleKeys[1], out.CompositeTraintupleKeys[3]}, keys)
+}
+
+func TestQueryWorkerTasksExactPage(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "algo")
+
+	// exactly one page of tasks
+	for i := 0; i < OutputPageSize; i++ {
+		inpTraintuple := inputTraintuple{Key: RandomUUID()}
+		resp := mockStub.MockInvoke(inpTraintuple.createDefault())
+		require.EqualValues(t, 200, resp.Status, resp.Message)
+	}
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	inp := inputQueryWorkerTasks{Worker: workerA, Statuses: []string{StatusTodo}}
+	tasks, bookmark, err := queryWorkerTasks(db, assetToArgs(inp))
+	require.NoError(t, err)
+	assert.Len(t, tasks, OutputPageSize)
+	assert.Equal(t, "", bookmark, "the last page should not point to another one")
+}
+
+func TestQueryWorkerTasks(t *testing.T) {
+	scc := new(SubstraChaincode)
+	mockStub := NewMockStubWithRegisterNode("substra", scc)
+	registerItem(t, *mockStub, "aggregateAlgo")
+	mockStub.MockTransactionStart("42")
+	db := NewLedgerDB(mockStub)
+
+	out, err := createComputePlanInternal(db, defaultComputePlan, tag, map[string]string{}, false)
+	require.NoError(t, err)
+
+	inp := inputQueryWorkerTasks{Worker: workerA, Statuses: []string{StatusTodo, StatusWaiting}}
+	tasks, _, err := queryWorkerTasks(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	require.Len(t, tasks, 3)
+	require.NotNil(t, tasks[0].Traintuple)
+	assert.Equal(t, out.TraintupleKeys[0], tasks[0].Traintuple.Key)
+	assert.Equal(t, StatusTodo, tasks[0].Traintuple.Status)
+
+	inp = inputQueryWorkerTasks{Worker: workerA, Statuses: []string{StatusTodo}, ComputePlanKey: out.Key}
+	tasks, _, err = queryWorkerTasks(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, out.TraintupleKeys[0], tasks[0].Traintuple.Key)
+
+	// The task queue follows the status updates
+	traintupleToDone(t, db, out.TraintupleKeys[0], modelKey)
+	tasks, _, err = queryWorkerTasks(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, out.TraintupleKeys[1], tasks[0].Traintuple.Key)
+
+	inp = inputQueryWorkerTasks{Worker: workerB, Statuses: []string{StatusTodo}}
+	tasks, _, err = queryWorkerTasks(db, assetToArgs(inp))
+	assert.NoError(t, err)
+	assert.Len(t, tasks, 0)
 }
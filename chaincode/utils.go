@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"time"
 
 	"gopkg.in/go-playground/validator.v9"
 
@@ -83,6 +84,16 @@ func GetTxCreator(stub shim.ChaincodeStubInterface) (string, error) {
 	return sID.GetMspid(), nil
 }
 
+// GetTxTime returns the transaction timestamp from the channel header, which
+// is the same for all the endorsers
+func GetTxTime(stub shim.ChaincodeStubInterface) (time.Time, error) {
+	timestamp, err := stub.GetTxTimestamp()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(timestamp.GetSeconds(), int64(timestamp.GetNanos())), nil
+}
+
 // String returns a string representation for an asset type
 func (assetType AssetType) String() string {
 	switch assetType {
@@ -108,6 +119,10 @@ func (assetType AssetType) String() string {
 		return "testtuple"
 	case ComputePlanType:
 		return "compute_plan"
+	case NodeGroupType:
+		return "node_group"
+	case ExternalModelType:
+		return "external_model"
 	default:
 		return fmt.Sprintf("(unknown asset type: %d)", assetType)
 	}